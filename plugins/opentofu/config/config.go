@@ -14,6 +14,27 @@
 
 package config
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Engine represents the command line tool used to execute the OpenTofu compatible commands.
+type Engine string
+
+const (
+	// EngineOpenTofu uses the "tofu" command.
+	EngineOpenTofu Engine = "opentofu"
+	// EngineTerraform uses the "terraform" command.
+	EngineTerraform Engine = "terraform"
+)
+
 // Config represents the plugin-scoped configuration.
 type Config struct{}
 
@@ -28,6 +49,69 @@ type DeployTargetConfig struct {
 	// Enable drift detection.
 	// TODO: This is a temporary option because  drift detection is buggy and has performance issues. This will be possibly removed in the future release.
 	DriftDetectionEnabled *bool `json:"driftDetectionEnabled" default:"true"`
+	// The client-side state encryption settings.
+	// This is only supported by the "opentofu" engine.
+	Encryption *OpenTofuEncryption `json:"encryption,omitempty"`
+}
+
+// KeyProviderPBKDF2 derives the encryption key from a passphrase.
+const KeyProviderPBKDF2 = "pbkdf2"
+
+// OpenTofuEncryption represents the client-side state encryption settings.
+type OpenTofuEncryption struct {
+	// The key used to encrypt the state and plan files.
+	Key OpenTofuEncryptionKey `json:"key"`
+	// The previous key used to decrypt the state and plan files that are not yet encrypted by the current key.
+	// Set this while rotating the key.
+	FallbackKey *OpenTofuEncryptionKey `json:"fallbackKey,omitempty"`
+}
+
+// OpenTofuEncryptionKey represents a key used for the client-side state encryption.
+type OpenTofuEncryptionKey struct {
+	// The key provider. Currently only "pbkdf2" is supported.
+	// Empty means "pbkdf2".
+	KeyProvider string `json:"keyProvider,omitempty"`
+	// The passphrase to derive the key from.
+	// Prefer passphraseFile to avoid writing the passphrase in the config.
+	Passphrase string `json:"passphrase,omitempty"`
+	// The path to the file containing the passphrase.
+	PassphraseFile string `json:"passphraseFile,omitempty"`
+}
+
+// Validate checks the encryption settings.
+func (e *OpenTofuEncryption) Validate() error {
+	if err := e.Key.Validate(); err != nil {
+		return fmt.Errorf("key: %w", err)
+	}
+	if e.FallbackKey != nil {
+		if err := e.FallbackKey.Validate(); err != nil {
+			return fmt.Errorf("fallbackKey: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the encryption key settings.
+func (k *OpenTofuEncryptionKey) Validate() error {
+	if k.KeyProvider != "" && k.KeyProvider != KeyProviderPBKDF2 {
+		return fmt.Errorf("unsupported key provider %q, currently only %q is supported", k.KeyProvider, KeyProviderPBKDF2)
+	}
+	if (k.Passphrase == "") == (k.PassphraseFile == "") {
+		return errors.New("exactly one of passphrase and passphraseFile must be set")
+	}
+	return nil
+}
+
+// LoadPassphrase returns the passphrase set directly or read from the passphrase file.
+func (k *OpenTofuEncryptionKey) LoadPassphrase() (string, error) {
+	if k.PassphraseFile == "" {
+		return k.Passphrase, nil
+	}
+	data, err := os.ReadFile(k.PassphraseFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
 }
 
 // ApplicationConfigSpec represents the application-scoped plugin config.
@@ -35,9 +119,15 @@ type ApplicationConfigSpec struct {
 	// The opentofu workspace name.
 	// Empty means "default" workspace.
 	Workspace string `json:"workspace,omitempty"`
+	// The engine used to execute the commands. Either "opentofu" or "terraform".
+	// Empty means "opentofu".
+	Engine Engine `json:"engine,omitempty"`
 	// The version of opentofu that should be used.
 	// Empty means the pre-installed version will be used.
 	OpenTofuVersion string `json:"openTofuVersion,omitempty"`
+	// The version of terraform that should be used when the engine is "terraform".
+	// Empty means the pre-installed version will be used.
+	TerraformVersion string `json:"terraformVersion,omitempty"`
 	// List of variables that will be set directly on opentofu commands with "-var" flag.
 	// The variable must be formatted by "key=value" as below:
 	// "image_id=ami-abc123"
@@ -50,6 +140,89 @@ type ApplicationConfigSpec struct {
 	CommandFlags OpenTofuCommandFlags `json:"commandFlags"`
 	// List of additional environment variables will be used while executing opentofu commands.
 	CommandEnvs OpenTofuCommandEnvs `json:"commandEnvs"`
+	// List of root modules of the application.
+	// Empty means the application directory is the only root module.
+	// Modules are planned and applied in the order resolved from their dependencies.
+	Modules []OpenTofuModule `json:"modules,omitempty"`
+}
+
+// OpenTofuModule represents a root module of an application consisting of multiple root modules.
+type OpenTofuModule struct {
+	// The unique name of the module.
+	Name string `json:"name"`
+	// The path to the module directory relative to the application directory.
+	Dir string `json:"dir"`
+	// The names of the modules that must be applied before this module.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// List of variables that will be set only on this module's commands with "-var" flag.
+	Vars []string `json:"vars,omitempty"`
+	// List of variable files that will be set only on this module's commands with "-var-file" flag.
+	VarFiles []string `json:"varFiles,omitempty"`
+	// Variables whose values are taken from the outputs of the upstream modules.
+	// The key is the variable name and the value is the output formatted by "<module name>.<output name>".
+	// The referenced module must be listed in dependsOn.
+	OutputVars map[string]string `json:"outputVars,omitempty"`
+}
+
+// ParseOutputRef parses the output reference formatted by "<module name>.<output name>".
+func ParseOutputRef(ref string) (module, output string, err error) {
+	module, output, ok := strings.Cut(ref, ".")
+	if !ok || module == "" || output == "" {
+		return "", "", fmt.Errorf("invalid output reference %q, must be formatted by <module name>.<output name>", ref)
+	}
+	return module, output, nil
+}
+
+// SortedModules returns the modules sorted in the order they must be applied.
+// Modules without dependencies on each other keep the order they are declared.
+func (s *ApplicationConfigSpec) SortedModules() ([]OpenTofuModule, error) {
+	indexes := make(map[string]int, len(s.Modules))
+	for i, m := range s.Modules {
+		if m.Name == "" {
+			return nil, fmt.Errorf("modules[%d]: name must not be empty", i)
+		}
+		if _, ok := indexes[m.Name]; ok {
+			return nil, fmt.Errorf("modules[%d]: duplicated module name %q", i, m.Name)
+		}
+		indexes[m.Name] = i
+	}
+
+	inDegrees := make([]int, len(s.Modules))
+	dependents := make([][]int, len(s.Modules))
+	for i, m := range s.Modules {
+		for _, d := range m.DependsOn {
+			j, ok := indexes[d]
+			if !ok {
+				return nil, fmt.Errorf("module %q depends on unknown module %q", m.Name, d)
+			}
+			if i == j {
+				return nil, fmt.Errorf("module %q depends on itself", m.Name)
+			}
+			inDegrees[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	sorted := make([]OpenTofuModule, 0, len(s.Modules))
+	done := make([]bool, len(s.Modules))
+	for len(sorted) < len(s.Modules) {
+		next := -1
+		for i := range s.Modules {
+			if !done[i] && inDegrees[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, errors.New("modules have a circular dependency")
+		}
+		done[next] = true
+		sorted = append(sorted, s.Modules[next])
+		for _, j := range dependents[next] {
+			inDegrees[j]--
+		}
+	}
+	return sorted, nil
 }
 
 // OpenTofuPlanStageOptions contains all configurable values for an OPENTOFU_PLAN stage.
@@ -60,6 +233,146 @@ type OpenTofuPlanStageOptions struct {
 
 // OpenTofuApplyStageOptions contains all configurable values for an OPENTOFU_APPLY stage.
 type OpenTofuApplyStageOptions struct {
+	// Run plan right after apply to check whether the infrastructure converged.
+	CheckConvergence bool `json:"checkConvergence"`
+	// The action taken when the plan after apply still shows changes.
+	// Either "fail" or "warn". Empty means "fail".
+	OnNonConvergence NonConvergenceAction `json:"onNonConvergence,omitempty"`
+	// Ordered waves of target address patterns to roll out the changes gradually.
+	// Each wave is applied with "-target" flags, then the remaining changes are applied at last.
+	Waves []OpenTofuApplyWave `json:"waves,omitempty"`
+}
+
+// OpenTofuApplyWave represents a subset of resources applied together.
+type OpenTofuApplyWave struct {
+	// The name of the wave shown in the log.
+	// Empty means "wave-<index>".
+	Name string `json:"name,omitempty"`
+	// List of target address patterns, e.g. 'module.region["us-east-1"]', 'module.region["us-*"]'.
+	// "*" matches any sequence of characters and is resolved against the addresses of the planned changes.
+	Targets []string `json:"targets"`
+	// How long to wait after applying this wave before starting the next one.
+	Wait Duration `json:"wait,omitempty"`
+	// The HTTP health probe that must pass after applying this wave before starting the next one.
+	HealthCheck *OpenTofuHealthCheck `json:"healthCheck,omitempty"`
+}
+
+// OpenTofuHealthCheck represents an HTTP health probe.
+type OpenTofuHealthCheck struct {
+	// The URL to send GET requests to.
+	URL string `json:"url"`
+	// The expected status code. Zero means any 2xx status code.
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+	// How long to keep probing until it passes. Zero means 1m.
+	Timeout Duration `json:"timeout,omitempty"`
+	// The interval between probes. Zero means 10s.
+	Interval Duration `json:"interval,omitempty"`
+}
+
+// Duration is a time.Duration configured by a string such as "30s" or "5m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// WaveName returns the name of the i-th wave.
+func (w *OpenTofuApplyWave) WaveName(i int) string {
+	if w.Name != "" {
+		return w.Name
+	}
+	return fmt.Sprintf("wave-%d", i)
+}
+
+// NonConvergenceAction represents the action taken when the infrastructure did not converge after apply.
+type NonConvergenceAction string
+
+const (
+	// NonConvergenceActionFail makes the stage fail.
+	NonConvergenceActionFail NonConvergenceAction = "fail"
+	// NonConvergenceActionWarn only reports a warning and makes the stage succeed.
+	NonConvergenceActionWarn NonConvergenceAction = "warn"
+)
+
+// Validate checks the OPENTOFU_APPLY stage options.
+func (o *OpenTofuApplyStageOptions) Validate() error {
+	switch o.OnNonConvergence {
+	case "", NonConvergenceActionFail, NonConvergenceActionWarn:
+	default:
+		return fmt.Errorf("unsupported onNonConvergence %q, must be either %q or %q", o.OnNonConvergence, NonConvergenceActionFail, NonConvergenceActionWarn)
+	}
+	for i, w := range o.Waves {
+		if len(w.Targets) == 0 {
+			return fmt.Errorf("waves[%d]: targets must not be empty", i)
+		}
+		if w.Wait < 0 {
+			return fmt.Errorf("waves[%d]: wait must not be negative", i)
+		}
+		if hc := w.HealthCheck; hc != nil {
+			if u, err := url.Parse(hc.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("waves[%d]: healthCheck.url must be a valid http or https URL", i)
+			}
+			if hc.Timeout < 0 || hc.Interval < 0 {
+				return fmt.Errorf("waves[%d]: healthCheck.timeout and healthCheck.interval must not be negative", i)
+			}
+		}
+	}
+	return nil
+}
+
+// OpenTofuImportStageOptions contains all configurable values for an OPENTOFU_IMPORT stage.
+type OpenTofuImportStageOptions struct {
+	// The name of the module to import the resources into.
+	// Required when the application has multiple modules.
+	Module string `json:"module,omitempty"`
+	// List of existing resources to be imported.
+	Imports []OpenTofuImport `json:"imports"`
+	// Allow the plan to contain changes other than the imports.
+	// Even if this is enabled, only the imported resources will be applied.
+	AllowOtherChanges bool `json:"allowOtherChanges"`
+}
+
+// OpenTofuImport represents an existing resource to be imported.
+type OpenTofuImport struct {
+	// The resource address to import the resource into.
+	// e.g. "aws_instance.example", 'module.network.aws_vpc.main["primary"]'
+	Address string `json:"address"`
+	// The provider-specific ID of the existing resource.
+	ID string `json:"id"`
+}
+
+// Validate checks the OPENTOFU_IMPORT stage options.
+func (o *OpenTofuImportStageOptions) Validate() error {
+	if len(o.Imports) == 0 {
+		return errors.New("imports must not be empty")
+	}
+	seen := make(map[string]struct{}, len(o.Imports))
+	for i, imp := range o.Imports {
+		if imp.Address == "" {
+			return fmt.Errorf("imports[%d]: address must not be empty", i)
+		}
+		if imp.ID == "" {
+			return fmt.Errorf("imports[%d]: id must not be empty", i)
+		}
+		if _, ok := seen[imp.Address]; ok {
+			return fmt.Errorf("imports[%d]: duplicated address %q", i, imp.Address)
+		}
+		seen[imp.Address] = struct{}{}
+	}
+	return nil
 }
 
 // OpenTofuCommandFlags contains all additional flags that will be used while executing opentofu commands.
@@ -68,6 +381,101 @@ type OpenTofuCommandFlags struct {
 	Init   []string `json:"init"`
 	Plan   []string `json:"plan"`
 	Apply  []string `json:"apply"`
+	// The number of concurrent operations while executing plan and apply.
+	// Zero means the default value of the engine.
+	Parallelism int `json:"parallelism,omitempty"`
+	// Whether to refresh the state before executing plan and apply.
+	// Empty means true. The refresh-only apply rotating the encryption key always refreshes.
+	Refresh *bool `json:"refresh,omitempty"`
+	// Show warnings in a compact form while executing plan and apply.
+	CompactWarnings bool `json:"compactWarnings,omitempty"`
+	// Show the plan in a concise form while executing plan and apply.
+	// This is only supported by the "opentofu" engine.
+	Concise bool `json:"concise,omitempty"`
+}
+
+// typedFlags are the flags configurable by the typed fields of OpenTofuCommandFlags.
+var typedFlags = map[string]string{
+	"-parallelism":      "parallelism",
+	"-refresh":          "refresh",
+	"-compact-warnings": "compactWarnings",
+	"-concise":          "concise",
+}
+
+// pluginManagedFlags are the flags set by the plugin for each command.
+var pluginManagedFlags = map[string][]string{
+	"init":  {},
+	"plan":  {"-lock", "-detailed-exitcode", "-target", "-out"},
+	"apply": {"-auto-approve", "-input", "-target", "-refresh-only"},
+}
+
+// flagName returns the name of the given flag such as "-parallelism" for "--parallelism=10".
+func flagName(flag string) string {
+	name, _, _ := strings.Cut(flag, "=")
+	if strings.HasPrefix(name, "--") {
+		name = name[1:]
+	}
+	return name
+}
+
+func validateFlags(field string, flags []string, managed []string) error {
+	for _, f := range flags {
+		name := flagName(f)
+		if typed, ok := typedFlags[name]; ok {
+			return fmt.Errorf("commandFlags.%s: %q must be configured by commandFlags.%s instead", field, f, typed)
+		}
+		if slices.Contains(managed, name) {
+			return fmt.Errorf("commandFlags.%s: %q conflicts with the flag managed by the plugin", field, f)
+		}
+	}
+	return nil
+}
+
+// Validate checks the flags do not duplicate or contradict the flags managed by the plugin.
+func (f *OpenTofuCommandFlags) Validate(engine Engine) error {
+	// Shared flags are passed to every command.
+	var allManaged []string
+	for _, managed := range pluginManagedFlags {
+		allManaged = append(allManaged, managed...)
+	}
+	if err := validateFlags("shared", f.Shared, allManaged); err != nil {
+		return err
+	}
+	if err := validateFlags("init", f.Init, pluginManagedFlags["init"]); err != nil {
+		return err
+	}
+	if err := validateFlags("plan", f.Plan, pluginManagedFlags["plan"]); err != nil {
+		return err
+	}
+	if err := validateFlags("apply", f.Apply, pluginManagedFlags["apply"]); err != nil {
+		return err
+	}
+
+	if f.Parallelism < 0 {
+		return fmt.Errorf("commandFlags.parallelism must not be negative, got %d", f.Parallelism)
+	}
+	if f.Concise && engine != EngineOpenTofu {
+		return fmt.Errorf("commandFlags.concise is not supported by the %q engine", engine)
+	}
+	return nil
+}
+
+// TuningFlags returns the flags for plan and apply commands built from the typed fields.
+func (f *OpenTofuCommandFlags) TuningFlags() []string {
+	var flags []string
+	if f.Parallelism > 0 {
+		flags = append(flags, fmt.Sprintf("-parallelism=%d", f.Parallelism))
+	}
+	if f.Refresh != nil && !*f.Refresh {
+		flags = append(flags, "-refresh=false")
+	}
+	if f.CompactWarnings {
+		flags = append(flags, "-compact-warnings")
+	}
+	if f.Concise {
+		flags = append(flags, "-concise")
+	}
+	return flags
 }
 
 // OpenTofuCommandEnvs contains all additional environment variables that will be used while executing opentofu commands.
@@ -78,7 +486,39 @@ type OpenTofuCommandEnvs struct {
 	Apply  []string `json:"apply"`
 }
 
+// EngineOrDefault returns the configured engine, or "opentofu" if it is not set.
+func (s *ApplicationConfigSpec) EngineOrDefault() Engine {
+	if s.Engine == "" {
+		return EngineOpenTofu
+	}
+	return s.Engine
+}
+
 func (s *ApplicationConfigSpec) Validate() error {
-	// TODO: Validate ApplicationConfigSpec fields.
+	switch s.Engine {
+	case "", EngineOpenTofu, EngineTerraform:
+	default:
+		return fmt.Errorf("unsupported engine %q, must be either %q or %q", s.Engine, EngineOpenTofu, EngineTerraform)
+	}
+	if err := s.CommandFlags.Validate(s.EngineOrDefault()); err != nil {
+		return err
+	}
+	if _, err := s.SortedModules(); err != nil {
+		return err
+	}
+	for _, m := range s.Modules {
+		if m.Dir == "" {
+			return fmt.Errorf("module %q: dir must not be empty", m.Name)
+		}
+		for name, ref := range m.OutputVars {
+			upstream, _, err := ParseOutputRef(ref)
+			if err != nil {
+				return fmt.Errorf("module %q: outputVars.%s: %w", m.Name, name, err)
+			}
+			if !slices.Contains(m.DependsOn, upstream) {
+				return fmt.Errorf("module %q: outputVars.%s refers to module %q which is not listed in dependsOn", m.Name, name, upstream)
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,426 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func moduleNames(modules []OpenTofuModule) []string {
+	names := make([]string, 0, len(modules))
+	for _, m := range modules {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestApplicationConfigSpec_SortedModules(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		modules     []OpenTofuModule
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "no modules",
+			expected: []string{},
+		},
+		{
+			name: "keep declared order without dependencies",
+			modules: []OpenTofuModule{
+				{Name: "a", Dir: "a"},
+				{Name: "b", Dir: "b"},
+			},
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "dependencies first",
+			modules: []OpenTofuModule{
+				{Name: "service", Dir: "service", DependsOn: []string{"database", "network"}},
+				{Name: "database", Dir: "database", DependsOn: []string{"network"}},
+				{Name: "network", Dir: "network"},
+				{Name: "monitoring", Dir: "monitoring"},
+			},
+			expected: []string{"network", "database", "service", "monitoring"},
+		},
+		{
+			name: "unknown dependency",
+			modules: []OpenTofuModule{
+				{Name: "a", Dir: "a", DependsOn: []string{"b"}},
+			},
+			expectedErr: `module "a" depends on unknown module "b"`,
+		},
+		{
+			name: "self dependency",
+			modules: []OpenTofuModule{
+				{Name: "a", Dir: "a", DependsOn: []string{"a"}},
+			},
+			expectedErr: `module "a" depends on itself`,
+		},
+		{
+			name: "circular dependency",
+			modules: []OpenTofuModule{
+				{Name: "a", Dir: "a", DependsOn: []string{"c"}},
+				{Name: "b", Dir: "b", DependsOn: []string{"a"}},
+				{Name: "c", Dir: "c", DependsOn: []string{"b"}},
+			},
+			expectedErr: "modules have a circular dependency",
+		},
+		{
+			name: "duplicated name",
+			modules: []OpenTofuModule{
+				{Name: "a", Dir: "a"},
+				{Name: "a", Dir: "b"},
+			},
+			expectedErr: `modules[1]: duplicated module name "a"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			spec := &ApplicationConfigSpec{Modules: tc.modules}
+			got, err := spec.SortedModules()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, moduleNames(got))
+		})
+	}
+}
+
+func TestApplicationConfigSpec_Validate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		spec        ApplicationConfigSpec
+		expectedErr string
+	}{
+		{
+			name: "no modules",
+			spec: ApplicationConfigSpec{},
+		},
+		{
+			name: "valid output vars",
+			spec: ApplicationConfigSpec{
+				Modules: []OpenTofuModule{
+					{Name: "network", Dir: "network"},
+					{Name: "service", Dir: "service", DependsOn: []string{"network"}, OutputVars: map[string]string{"vpc_id": "network.vpc_id"}},
+				},
+			},
+		},
+		{
+			name: "terraform engine",
+			spec: ApplicationConfigSpec{Engine: EngineTerraform},
+		},
+		{
+			name:        "unsupported engine",
+			spec:        ApplicationConfigSpec{Engine: "pulumi"},
+			expectedErr: `unsupported engine "pulumi", must be either "opentofu" or "terraform"`,
+		},
+		{
+			name: "empty dir",
+			spec: ApplicationConfigSpec{
+				Modules: []OpenTofuModule{{Name: "network"}},
+			},
+			expectedErr: `module "network": dir must not be empty`,
+		},
+		{
+			name: "malformed output reference",
+			spec: ApplicationConfigSpec{
+				Modules: []OpenTofuModule{
+					{Name: "network", Dir: "network"},
+					{Name: "service", Dir: "service", DependsOn: []string{"network"}, OutputVars: map[string]string{"vpc_id": "network"}},
+				},
+			},
+			expectedErr: `module "service": outputVars.vpc_id: invalid output reference "network", must be formatted by <module name>.<output name>`,
+		},
+		{
+			name: "output of module not in dependsOn",
+			spec: ApplicationConfigSpec{
+				Modules: []OpenTofuModule{
+					{Name: "network", Dir: "network"},
+					{Name: "service", Dir: "service", OutputVars: map[string]string{"vpc_id": "network.vpc_id"}},
+				},
+			},
+			expectedErr: `module "service": outputVars.vpc_id refers to module "network" which is not listed in dependsOn`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.spec.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenTofuImportStageOptions_Validate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		opts        OpenTofuImportStageOptions
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			opts: OpenTofuImportStageOptions{Imports: []OpenTofuImport{{Address: "aws_instance.a", ID: "i-1"}}},
+		},
+		{
+			name:        "empty imports",
+			opts:        OpenTofuImportStageOptions{},
+			expectedErr: "imports must not be empty",
+		},
+		{
+			name:        "empty id",
+			opts:        OpenTofuImportStageOptions{Imports: []OpenTofuImport{{Address: "aws_instance.a"}}},
+			expectedErr: "imports[0]: id must not be empty",
+		},
+		{
+			name: "duplicated address",
+			opts: OpenTofuImportStageOptions{Imports: []OpenTofuImport{
+				{Address: "aws_instance.a", ID: "i-1"},
+				{Address: "aws_instance.a", ID: "i-2"},
+			}},
+			expectedErr: `imports[1]: duplicated address "aws_instance.a"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.opts.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenTofuEncryption_Validate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		enc         OpenTofuEncryption
+		expectedErr string
+	}{
+		{
+			name: "passphrase",
+			enc:  OpenTofuEncryption{Key: OpenTofuEncryptionKey{Passphrase: "passphrase"}},
+		},
+		{
+			name: "passphrase file with fallback",
+			enc: OpenTofuEncryption{
+				Key:         OpenTofuEncryptionKey{KeyProvider: KeyProviderPBKDF2, PassphraseFile: "/etc/piped/key"},
+				FallbackKey: &OpenTofuEncryptionKey{Passphrase: "passphrase"},
+			},
+		},
+		{
+			name:        "unsupported key provider",
+			enc:         OpenTofuEncryption{Key: OpenTofuEncryptionKey{KeyProvider: "aws_kms", Passphrase: "passphrase"}},
+			expectedErr: `key: unsupported key provider "aws_kms", currently only "pbkdf2" is supported`,
+		},
+		{
+			name:        "no passphrase",
+			enc:         OpenTofuEncryption{Key: OpenTofuEncryptionKey{}},
+			expectedErr: "key: exactly one of passphrase and passphraseFile must be set",
+		},
+		{
+			name: "both passphrase and file in fallback",
+			enc: OpenTofuEncryption{
+				Key:         OpenTofuEncryptionKey{Passphrase: "passphrase"},
+				FallbackKey: &OpenTofuEncryptionKey{Passphrase: "passphrase", PassphraseFile: "/etc/piped/key"},
+			},
+			expectedErr: "fallbackKey: exactly one of passphrase and passphraseFile must be set",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.enc.Validate()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenTofuEncryptionKey_LoadPassphrase(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("from-file-passphrase\n"), 0600))
+
+	got, err := (&OpenTofuEncryptionKey{PassphraseFile: path}).LoadPassphrase()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file-passphrase", got)
+
+	got, err = (&OpenTofuEncryptionKey{Passphrase: "inline-passphrase"}).LoadPassphrase()
+	require.NoError(t, err)
+	assert.Equal(t, "inline-passphrase", got)
+
+	_, err = (&OpenTofuEncryptionKey{PassphraseFile: filepath.Join(t.TempDir(), "missing")}).LoadPassphrase()
+	assert.Error(t, err)
+}
+
+func TestOpenTofuCommandFlags_Validate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		flags       OpenTofuCommandFlags
+		engine      Engine
+		expectedErr string
+	}{
+		{
+			name:        "compact warnings in free-form flags",
+			flags:       OpenTofuCommandFlags{Plan: []string{"-compact-warnings=false"}},
+			engine:      EngineOpenTofu,
+			expectedErr: `commandFlags.plan: "-compact-warnings=false" must be configured by commandFlags.compactWarnings instead`,
+		},
+		{
+			name: "no conflicts",
+			flags: OpenTofuCommandFlags{
+				Shared:      []string{"-lock-timeout=5m"},
+				Init:        []string{"-upgrade", "-input=false"},
+				Apply:       []string{"-replace=aws_instance.a"},
+				Parallelism: 20,
+				Concise:     true,
+			},
+			engine: EngineOpenTofu,
+		},
+		{
+			name:        "parallelism in free-form flags",
+			flags:       OpenTofuCommandFlags{Apply: []string{"-parallelism=5"}},
+			engine:      EngineOpenTofu,
+			expectedErr: `commandFlags.apply: "-parallelism=5" must be configured by commandFlags.parallelism instead`,
+		},
+		{
+			name:        "double dash refresh",
+			flags:       OpenTofuCommandFlags{Shared: []string{"--refresh=false"}},
+			engine:      EngineOpenTofu,
+			expectedErr: `commandFlags.shared: "--refresh=false" must be configured by commandFlags.refresh instead`,
+		},
+		{
+			name:        "auto approve in apply",
+			flags:       OpenTofuCommandFlags{Apply: []string{"-auto-approve"}},
+			engine:      EngineOpenTofu,
+			expectedErr: `commandFlags.apply: "-auto-approve" conflicts with the flag managed by the plugin`,
+		},
+		{
+			name:        "detailed exitcode in shared",
+			flags:       OpenTofuCommandFlags{Shared: []string{"-detailed-exitcode"}},
+			engine:      EngineOpenTofu,
+			expectedErr: `commandFlags.shared: "-detailed-exitcode" conflicts with the flag managed by the plugin`,
+		},
+		{
+			name:        "negative parallelism",
+			flags:       OpenTofuCommandFlags{Parallelism: -1},
+			engine:      EngineOpenTofu,
+			expectedErr: "commandFlags.parallelism must not be negative, got -1",
+		},
+		{
+			name:        "concise with terraform",
+			flags:       OpenTofuCommandFlags{Concise: true},
+			engine:      EngineTerraform,
+			expectedErr: `commandFlags.concise is not supported by the "terraform" engine`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.flags.Validate(tc.engine)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenTofuCommandFlags_TuningFlags(t *testing.T) {
+	t.Parallel()
+
+	refresh := false
+	flags := OpenTofuCommandFlags{
+		Parallelism:     5,
+		Refresh:         &refresh,
+		CompactWarnings: true,
+		Concise:         true,
+	}
+	assert.Equal(t, []string{"-parallelism=5", "-refresh=false", "-compact-warnings", "-concise"}, flags.TuningFlags())
+
+	refresh = true
+	assert.Empty(t, (&OpenTofuCommandFlags{Refresh: &refresh}).TuningFlags())
+}
+
+func TestOpenTofuApplyStageOptions_Validate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, (&OpenTofuApplyStageOptions{}).Validate())
+	assert.NoError(t, (&OpenTofuApplyStageOptions{CheckConvergence: true, OnNonConvergence: NonConvergenceActionWarn}).Validate())
+	assert.EqualError(t, (&OpenTofuApplyStageOptions{OnNonConvergence: "ignore"}).Validate(), `unsupported onNonConvergence "ignore", must be either "fail" or "warn"`)
+}
+
+func TestOpenTofuApplyStageOptions_Waves(t *testing.T) {
+	t.Parallel()
+
+	var opts OpenTofuApplyStageOptions
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "waves": [
+    {"name": "canary", "targets": ["module.region[\"us-east-1\"]"], "wait": "5m"},
+    {"targets": ["module.region[\"us-*\"]"], "healthCheck": {"url": "https://example.com/healthz", "timeout": "2m"}}
+  ]
+}`), &opts))
+	require.NoError(t, opts.Validate())
+
+	assert.Equal(t, "canary", opts.Waves[0].WaveName(0))
+	assert.Equal(t, Duration(5*time.Minute), opts.Waves[0].Wait)
+	assert.Equal(t, "wave-1", opts.Waves[1].WaveName(1))
+	assert.Equal(t, Duration(2*time.Minute), opts.Waves[1].HealthCheck.Timeout)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"waves": [{"targets": ["a.b"], "wait": 10}]}`), &OpenTofuApplyStageOptions{}))
+	assert.EqualError(t, (&OpenTofuApplyStageOptions{Waves: []OpenTofuApplyWave{{}}}).Validate(), "waves[0]: targets must not be empty")
+	assert.EqualError(t,
+		(&OpenTofuApplyStageOptions{Waves: []OpenTofuApplyWave{{Targets: []string{"a.b"}, HealthCheck: &OpenTofuHealthCheck{URL: "example.com"}}}}).Validate(),
+		"waves[0]: healthCheck.url must be a valid http or https URL",
+	)
+}
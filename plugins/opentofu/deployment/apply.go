@@ -17,31 +17,58 @@ package deployment
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 
 	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
 )
 
 func (p *Plugin) executeApplyStage(ctx context.Context, input *sdk.ExecuteStageInput[config.ApplicationConfigSpec], dts []*sdk.DeployTarget[config.DeployTargetConfig]) sdk.StageStatus {
 	lp := input.Client.LogPersister()
 	lp.Info("Starting OpenTofu apply stage")
 
-	cmd, err := initOpenTofuCommand(ctx, input.Client, input.Request.TargetDeploymentSource, dts[0])
-	if err != nil {
-		lp.Errorf("Failed to initialize OpenTofu command: %v", err)
-		return sdk.StageStatusFailure
-	}
+	ds := input.Request.TargetDeploymentSource
 
 	var stageConfig config.OpenTofuApplyStageOptions
 	if err := json.Unmarshal(input.Request.StageConfig, &stageConfig); err != nil {
 		lp.Errorf("Failed to unmarshal stage config (%v)", err)
 		return sdk.StageStatusFailure
 	}
+	if err := stageConfig.Validate(); err != nil {
+		lp.Errorf("Invalid stage config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	modules, err := rootModules(ds.ApplicationConfig.Spec)
+	if err != nil {
+		lp.Errorf("Failed to resolve modules (%v)", err)
+		return sdk.StageStatusFailure
+	}
 
-	lp.Infof("Start executing apply.")
+	err = forEachModule(ctx, input.Client, ds, dts[0], modules, true, func(m config.OpenTofuModule, cmd *provider.OpenTofu) error {
+		lp.Infof("Start executing apply%s.", moduleSuffix(m))
 
-	if err := cmd.Apply(ctx, lp); err != nil {
+		if len(stageConfig.Waves) > 0 {
+			if err := applyWaves(ctx, cmd, m, stageConfig.Waves, lp); err != nil {
+				return err
+			}
+		} else if err := cmd.Apply(ctx, lp); err != nil {
+			return fmt.Errorf("failed to apply%s: %w", moduleSuffix(m), err)
+		}
+
+		if m.Name != "" {
+			lp.Successf("Successfully applied changes%s", moduleSuffix(m))
+		}
+
+		if stageConfig.CheckConvergence {
+			return checkConvergence(ctx, cmd, m, stageConfig.OnNonConvergence, lp)
+		}
+		return nil
+	})
+	if err != nil {
 		lp.Errorf("Failed to Apply (%v)", err)
 		return sdk.StageStatusFailure
 	}
@@ -49,3 +76,31 @@ func (p *Plugin) executeApplyStage(ctx context.Context, input *sdk.ExecuteStageI
 	lp.Success("Successfully applied changes")
 	return sdk.StageStatusSuccess
 }
+
+// checkConvergence runs plan right after apply and reports whether the infrastructure converged.
+func checkConvergence(ctx context.Context, cmd *provider.OpenTofu, m config.OpenTofuModule, action config.NonConvergenceAction, lp sdk.StageLogPersister) error {
+	lp.Infof("Start checking convergence%s.", moduleSuffix(m))
+
+	planResult, err := cmd.Plan(ctx, lp)
+	if err != nil {
+		return fmt.Errorf("failed to plan for convergence check%s: %w", moduleSuffix(m), err)
+	}
+
+	if planResult.NoChanges() {
+		lp.Successf("Infrastructure converged%s", moduleSuffix(m))
+		return nil
+	}
+
+	diff, err := planResult.Render()
+	if err != nil || diff == "" {
+		diff = planResult.PlanOutput
+	}
+	msg := fmt.Sprintf("Infrastructure did not converge%s, the plan after apply still shows %d import, %d add, %d change, %d destroy", moduleSuffix(m), planResult.Imports, planResult.Adds, planResult.Changes, planResult.Destroys)
+
+	if action == config.NonConvergenceActionWarn {
+		lp.Infof("WARNING: %s:\n%s", msg, diff)
+		return nil
+	}
+	lp.Errorf("%s:\n%s", msg, diff)
+	return errors.New("infrastructure did not converge")
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
+)
+
+// writeFakeTofu writes a script that behaves like "tofu plan -detailed-exitcode" with the given output and exit code.
+func writeFakeTofu(t *testing.T, output string, exitCode int) string {
+	t.Helper()
+	dir := t.TempDir()
+	out := filepath.Join(dir, "output")
+	require.NoError(t, os.WriteFile(out, []byte(output), 0644))
+	path := filepath.Join(dir, "tofu")
+	script := "#!/bin/sh\ncat " + out + "\nexit " + strconv.Itoa(exitCode) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestCheckConvergence(t *testing.T) {
+	t.Parallel()
+
+	const diffOutput = `
+OpenTofu will perform the following actions:
+  ~ resource "aws_instance" "example" {
+      ~ tags = {}
+    }
+
+Plan: 0 to add, 1 to change, 0 to destroy.
+`
+
+	testcases := []struct {
+		name        string
+		output      string
+		exitCode    int
+		action      config.NonConvergenceAction
+		expectedErr bool
+	}{
+		{
+			name:     "converged",
+			exitCode: 0,
+		},
+		{
+			name:        "not converged with fail",
+			output:      diffOutput,
+			exitCode:    2,
+			action:      config.NonConvergenceActionFail,
+			expectedErr: true,
+		},
+		{
+			name:        "not converged with default action",
+			output:      diffOutput,
+			exitCode:    2,
+			expectedErr: true,
+		},
+		{
+			name:     "not converged with warn",
+			output:   diffOutput,
+			exitCode: 2,
+			action:   config.NonConvergenceActionWarn,
+		},
+		{
+			name:        "plan failure",
+			exitCode:    1,
+			action:      config.NonConvergenceActionWarn,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := provider.NewOpenTofu(writeFakeTofu(t, tc.output, tc.exitCode), t.TempDir(), provider.WithoutColor())
+			err := checkConvergence(t.Context(), cmd, config.OpenTofuModule{}, tc.action, logpersistertest.NewTestLogPersister(t))
+			assert.Equal(t, tc.expectedErr, err != nil)
+		})
+	}
+}
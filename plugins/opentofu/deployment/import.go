@@ -0,0 +1,113 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
+)
+
+func (p *Plugin) executeImportStage(ctx context.Context, input *sdk.ExecuteStageInput[config.ApplicationConfigSpec], dts []*sdk.DeployTarget[config.DeployTargetConfig]) sdk.StageStatus {
+	lp := input.Client.LogPersister()
+	lp.Info("Starting OpenTofu import stage")
+
+	var stageConfig config.OpenTofuImportStageOptions
+	if err := json.Unmarshal(input.Request.StageConfig, &stageConfig); err != nil {
+		lp.Errorf("Failed to unmarshal stage config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	if err := stageConfig.Validate(); err != nil {
+		lp.Errorf("Invalid stage config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	imports := make([]provider.Import, 0, len(stageConfig.Imports))
+	addresses := make([]string, 0, len(stageConfig.Imports))
+	for _, imp := range stageConfig.Imports {
+		imports = append(imports, provider.Import{Address: imp.Address, ID: imp.ID})
+		addresses = append(addresses, imp.Address)
+	}
+
+	ds := input.Request.TargetDeploymentSource
+	modules, err := rootModules(ds.ApplicationConfig.Spec)
+	if err != nil {
+		lp.Errorf("Failed to resolve modules (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	modules, err = findModule(modules, stageConfig.Module)
+	if err != nil {
+		lp.Errorf("Failed to find the module to import into (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	target := modules[len(modules)-1]
+
+	importFile, err := provider.WriteImportFile(filepath.Join(ds.ApplicationDirectory, target.Dir), imports)
+	if err != nil {
+		lp.Errorf("Failed to prepare import blocks (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	// Remove the import blocks after the stage so that the later stages in the same workspace do not reuse them.
+	defer os.Remove(importFile)
+
+	var imported int
+	err = forEachModule(ctx, input.Client, ds, dts[0], modules, true, func(m config.OpenTofuModule, cmd *provider.OpenTofu) error {
+		if m.Name != target.Name {
+			return nil
+		}
+
+		planResult, err := cmd.Plan(ctx, lp)
+		if err != nil {
+			return fmt.Errorf("failed to plan%s: %w", moduleSuffix(m), err)
+		}
+
+		if planResult.Imports == 0 {
+			lp.Success("No resources to import")
+			return nil
+		}
+
+		if planResult.Adds > 0 || planResult.Changes > 0 || planResult.Destroys > 0 {
+			if !stageConfig.AllowOtherChanges {
+				return fmt.Errorf("the plan contains %d add, %d change, %d destroy besides the imports, set allowOtherChanges to proceed with importing only", planResult.Adds, planResult.Changes, planResult.Destroys)
+			}
+			lp.Infof("The plan contains %d add, %d change, %d destroy besides the imports. They will not be applied by this stage.", planResult.Adds, planResult.Changes, planResult.Destroys)
+		}
+
+		lp.Infof("Start importing %d resources%s.", planResult.Imports, moduleSuffix(m))
+
+		if err := cmd.Apply(ctx, lp, provider.WithTargets(addresses...)); err != nil {
+			return fmt.Errorf("failed to import: %w", err)
+		}
+		imported = planResult.Imports
+		return nil
+	})
+	if err != nil {
+		lp.Errorf("Failed to import resources (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	if imported == 0 {
+		return sdk.StageStatusSuccess
+	}
+
+	lp.Successf("Successfully imported %d resources", imported)
+	return sdk.StageStatusSuccess
+}
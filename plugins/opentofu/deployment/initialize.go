@@ -17,6 +17,10 @@ package deployment
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 
 	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
 	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
@@ -25,28 +29,163 @@ import (
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 )
 
-func initOpenTofuCommand(ctx context.Context, client *sdk.Client, ds sdk.DeploymentSource[config.ApplicationConfigSpec], dt *sdk.DeployTarget[config.DeployTargetConfig]) (*provider.OpenTofu, error) {
+// rootModules returns the root modules of the application in the order they must be applied.
+// When no module is configured, the application directory is treated as the only root module.
+func rootModules(spec *config.ApplicationConfigSpec) ([]config.OpenTofuModule, error) {
+	if spec == nil || len(spec.Modules) == 0 {
+		return []config.OpenTofuModule{{Dir: "."}}, nil
+	}
+	return spec.SortedModules()
+}
+
+// findModule returns the module with the given name and its upstream modules in the order they must be applied.
+func findModule(modules []config.OpenTofuModule, name string) ([]config.OpenTofuModule, error) {
+	if name == "" {
+		if len(modules) > 1 {
+			return nil, errors.New("module must be specified when the application has multiple modules")
+		}
+		return modules, nil
+	}
+
+	needed := map[string]bool{name: true}
+	found := false
+	// Modules are sorted so that dependents come after their dependencies, walk them backward.
+	for i := len(modules) - 1; i >= 0; i-- {
+		m := modules[i]
+		if !needed[m.Name] {
+			continue
+		}
+		if m.Name == name {
+			found = true
+		}
+		for _, d := range m.DependsOn {
+			needed[d] = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("module %q is not found", name)
+	}
+
+	out := make([]config.OpenTofuModule, 0, len(needed))
+	for _, m := range modules {
+		if needed[m.Name] {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// moduleSuffix returns the suffix to be appended to log messages to identify the module.
+func moduleSuffix(m config.OpenTofuModule) string {
+	if m.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" in module %q", m.Name)
+}
+
+// forEachModule initializes the opentofu command for each given module in order and calls fn with it.
+// The outputs of the upstream modules are passed to the downstream modules as variables.
+// Sensitive outputs are passed with TF_VAR_<name> environment variables instead of "-var" flags
+// since the command line is shown in the stage log.
+// When requireOutputs is false, variables referring to missing outputs are skipped instead of returning an error,
+// this allows planning the modules whose upstream modules have not been applied yet.
+func forEachModule(
+	ctx context.Context,
+	client *sdk.Client,
+	ds sdk.DeploymentSource[config.ApplicationConfigSpec],
+	dt *sdk.DeployTarget[config.DeployTargetConfig],
+	modules []config.OpenTofuModule,
+	requireOutputs bool,
+	fn func(m config.OpenTofuModule, cmd *provider.OpenTofu) error,
+) error {
+	lp := client.LogPersister()
+
+	referenced := make(map[string]bool)
+	for _, m := range modules {
+		for _, ref := range m.OutputVars {
+			if upstream, _, err := config.ParseOutputRef(ref); err == nil {
+				referenced[upstream] = true
+			}
+		}
+	}
+
+	outputs := make(map[string]map[string]provider.OutputValue)
+	for _, m := range modules {
+		if m.Name != "" {
+			lp.Infof("Processing module %q at %s", m.Name, m.Dir)
+		}
+
+		vars, envs, err := resolveOutputVars(m, outputs, requireOutputs, lp)
+		if err != nil {
+			return err
+		}
+
+		cmd, err := initOpenTofuCommand(ctx, client, ds, dt, m, vars, envs)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(m, cmd); err != nil {
+			return err
+		}
+
+		if referenced[m.Name] {
+			out, err := cmd.Output(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get outputs of module %q: %w", m.Name, err)
+			}
+			outputs[m.Name] = out
+		}
+	}
+	return nil
+}
+
+// resolveOutputVars returns the variables of the module referring to the outputs of the upstream modules.
+// The non-sensitive ones are returned as "-var" values, and the sensitive ones as TF_VAR_<name> environment variables.
+// Note that the environment variables have lower precedence than the variables set by "-var" flags and var files.
+func resolveOutputVars(m config.OpenTofuModule, outputs map[string]map[string]provider.OutputValue, requireOutputs bool, lp sdk.StageLogPersister) (vars, envs []string, err error) {
+	names := slices.Sorted(maps.Keys(m.OutputVars))
+	for _, name := range names {
+		upstream, output, err := config.ParseOutputRef(m.OutputVars[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		v, ok := outputs[upstream][output]
+		if !ok {
+			if requireOutputs {
+				return nil, nil, fmt.Errorf("output %q of module %q is not found", output, upstream)
+			}
+			lp.Infof("Skipped variable %q of module %q because output %q of module %q is not found", name, m.Name, output, upstream)
+			continue
+		}
+		if v.Sensitive {
+			envs = append(envs, fmt.Sprintf("TF_VAR_%s=%s", name, v.Value))
+			continue
+		}
+		vars = append(vars, fmt.Sprintf("%s=%s", name, v.Value))
+	}
+	return vars, envs, nil
+}
+
+func initOpenTofuCommand(ctx context.Context, client *sdk.Client, ds sdk.DeploymentSource[config.ApplicationConfigSpec], dt *sdk.DeployTarget[config.DeployTargetConfig], m config.OpenTofuModule, outputVars, outputEnvs []string) (*provider.OpenTofu, error) {
 	var (
 		appSpec = ds.ApplicationConfig.Spec
-		flags   = appSpec.CommandFlags
-		envs    = appSpec.CommandEnvs
 		lp      = client.LogPersister()
 	)
-	tr := toolregistry.NewRegistry(client.ToolRegistry())
-	opentofuPath, err := tr.OpenTofu(ctx, appSpec.OpenTofuVersion)
+	execPath, engine, err := installEngine(ctx, client, appSpec)
 	if err != nil {
-		lp.Errorf("Failed to find opentofu (%v)", err)
+		lp.Errorf("Failed to find %s (%v)", appSpec.EngineOrDefault(), err)
 		return nil, err
 	}
 
-	cmd := provider.NewOpenTofu(
-		opentofuPath,
-		ds.ApplicationDirectory,
-		provider.WithVars(mergeVars(dt.Config.Vars, appSpec.Vars)),
-		provider.WithVarFiles(appSpec.VarFiles),
-		provider.WithAdditionalFlags(flags.Shared, flags.Init, flags.Plan, flags.Apply),
-		provider.WithAdditionalEnvs(envs.Shared, envs.Init, envs.Plan, envs.Apply),
-	)
+	dir := filepath.Join(ds.ApplicationDirectory, m.Dir)
+	opts, err := commandOptions(ds, dt, m, engine, dir, outputVars, outputEnvs)
+	if err != nil {
+		lp.Errorf("Failed to configure state encryption (%v)", err)
+		return nil, err
+	}
+
+	cmd := provider.NewOpenTofu(execPath, dir, opts...)
 
 	if ok := showUsingVersion(ctx, cmd, lp); !ok {
 		return nil, errors.New("failed to show using version")
@@ -64,6 +203,105 @@ func initOpenTofuCommand(ctx context.Context, client *sdk.Client, ds sdk.Deploym
 	return cmd, nil
 }
 
+// commandOptions returns the options of the command for the module in dir built from the configs.
+func commandOptions(ds sdk.DeploymentSource[config.ApplicationConfigSpec], dt *sdk.DeployTarget[config.DeployTargetConfig], m config.OpenTofuModule, engine provider.Engine, dir string, outputVars, outputEnvs []string) ([]provider.Option, error) {
+	var (
+		appSpec = ds.ApplicationConfig.Spec
+		flags   = appSpec.CommandFlags
+		envs    = appSpec.CommandEnvs
+	)
+	vars := mergeVars(dt.Config.Vars, appSpec.Vars)
+	vars = append(vars, m.Vars...)
+	vars = append(vars, outputVars...)
+
+	opts := []provider.Option{
+		provider.WithEngine(engine),
+		provider.WithVars(vars),
+		provider.WithVarFiles(append(appVarFiles(ds.ApplicationDirectory, dir, appSpec.VarFiles), m.VarFiles...)),
+		provider.WithAdditionalFlags(nil, nil, flags.TuningFlags(), flags.TuningFlags()),
+		provider.WithAdditionalFlags(flags.Shared, flags.Init, flags.Plan, flags.Apply),
+		provider.WithAdditionalEnvs(envs.Shared, envs.Init, envs.Plan, envs.Apply),
+		provider.WithAdditionalEnvs(outputEnvs, nil, nil, nil),
+	}
+	if enc := dt.Config.Encryption; enc != nil {
+		opt, err := encryptionOption(engine, enc)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
+
+// installEngine installs the binary of the configured engine and returns the path to it.
+func installEngine(ctx context.Context, client *sdk.Client, spec *config.ApplicationConfigSpec) (string, provider.Engine, error) {
+	tr := toolregistry.NewRegistry(client.ToolRegistry())
+	switch engine := spec.EngineOrDefault(); engine {
+	case config.EngineOpenTofu:
+		path, err := tr.OpenTofu(ctx, spec.OpenTofuVersion)
+		return path, provider.EngineOpenTofu, err
+	case config.EngineTerraform:
+		path, err := tr.Terraform(ctx, spec.TerraformVersion)
+		return path, provider.EngineTerraform, err
+	default:
+		return "", "", fmt.Errorf("unsupported engine %q", engine)
+	}
+}
+
+// encryptionOption loads the encryption keys configured for the deploy target.
+func encryptionOption(engine provider.Engine, enc *config.OpenTofuEncryption) (provider.Option, error) {
+	if engine != provider.EngineOpenTofu {
+		return nil, errors.New("state encryption is only supported by the opentofu engine")
+	}
+	if err := enc.Validate(); err != nil {
+		return nil, err
+	}
+
+	key, err := loadEncryptionKey(enc.Key)
+	if err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+
+	var fallback *provider.EncryptionKey
+	if enc.FallbackKey != nil {
+		k, err := loadEncryptionKey(*enc.FallbackKey)
+		if err != nil {
+			return nil, fmt.Errorf("fallbackKey: %w", err)
+		}
+		fallback = &k
+	}
+
+	return provider.WithEncryption(key, fallback), nil
+}
+
+func loadEncryptionKey(k config.OpenTofuEncryptionKey) (provider.EncryptionKey, error) {
+	passphrase, err := k.LoadPassphrase()
+	if err != nil {
+		return provider.EncryptionKey{}, err
+	}
+	key := provider.EncryptionKey{Passphrase: passphrase}
+	if err := key.Validate(); err != nil {
+		return provider.EncryptionKey{}, err
+	}
+	return key, nil
+}
+
+// appVarFiles returns the application-scoped variable files which are relative to the application directory
+// as paths usable from the given module directory.
+func appVarFiles(appDir, moduleDir string, files []string) []string {
+	if filepath.Clean(appDir) == filepath.Clean(moduleDir) {
+		return files
+	}
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(appDir, f)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
 func mergeVars(deployTargetVars []string, appVars []string) []string {
 	// TODO: Validate duplication
 	mergedVars := make([]string, 0, len(deployTargetVars)+len(appVars))
@@ -78,7 +316,7 @@ func showUsingVersion(ctx context.Context, cmd *provider.OpenTofu, lp sdk.StageL
 		lp.Errorf("Failed to check opentofu version (%v)", err)
 		return false
 	}
-	lp.Infof("Using opentofu version %q to execute the opentofu commands", version)
+	lp.Infof("Using version %q to execute the commands", version)
 	return true
 }
 
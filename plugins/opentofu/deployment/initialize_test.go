@@ -17,7 +17,12 @@ package deployment
 import (
 	"testing"
 
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
 )
 
 func TestMergeVars(t *testing.T) {
@@ -70,3 +75,125 @@ func TestMergeVars(t *testing.T) {
 		})
 	}
 }
+
+func TestFindModule(t *testing.T) {
+	t.Parallel()
+
+	modules := []config.OpenTofuModule{
+		{Name: "network", Dir: "network"},
+		{Name: "database", Dir: "database", DependsOn: []string{"network"}},
+		{Name: "monitoring", Dir: "monitoring"},
+		{Name: "service", Dir: "service", DependsOn: []string{"database"}},
+	}
+
+	testcases := []struct {
+		name        string
+		modules     []config.OpenTofuModule
+		target      string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "single root module",
+			modules:  []config.OpenTofuModule{{Dir: "."}},
+			expected: []string{""},
+		},
+		{
+			name:        "module must be specified",
+			modules:     modules,
+			expectedErr: true,
+		},
+		{
+			name:     "with transitive upstream modules",
+			modules:  modules,
+			target:   "service",
+			expected: []string{"network", "database", "service"},
+		},
+		{
+			name:     "without upstream modules",
+			modules:  modules,
+			target:   "monitoring",
+			expected: []string{"monitoring"},
+		},
+		{
+			name:        "unknown module",
+			modules:     modules,
+			target:      "unknown",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := findModule(tc.modules, tc.target)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if err != nil {
+				return
+			}
+			names := make([]string, 0, len(got))
+			for _, m := range got {
+				names = append(names, m.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestResolveOutputVars(t *testing.T) {
+	t.Parallel()
+
+	m := config.OpenTofuModule{
+		Name:      "service",
+		DependsOn: []string{"network"},
+		OutputVars: map[string]string{
+			"vpc_id":      "network.vpc_id",
+			"subnet_ids":  "network.subnet_ids",
+			"db_password": "network.db_password",
+		},
+	}
+	outputs := map[string]map[string]provider.OutputValue{
+		"network": {
+			"vpc_id":      {Value: "vpc-123"},
+			"subnet_ids":  {Value: `["subnet-1","subnet-2"]`},
+			"db_password": {Value: "s3cret", Sensitive: true},
+		},
+	}
+
+	vars, envs, err := resolveOutputVars(m, outputs, true, logpersistertest.NewTestLogPersister(t))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`subnet_ids=["subnet-1","subnet-2"]`, "vpc_id=vpc-123"}, vars)
+	// Sensitive outputs are not passed on the command line.
+	assert.Equal(t, []string{"TF_VAR_db_password=s3cret"}, envs)
+
+	_, _, err = resolveOutputVars(m, map[string]map[string]provider.OutputValue{}, true, logpersistertest.NewTestLogPersister(t))
+	assert.Error(t, err)
+
+	vars, envs, err = resolveOutputVars(m, map[string]map[string]provider.OutputValue{}, false, logpersistertest.NewTestLogPersister(t))
+	require.NoError(t, err)
+	assert.Empty(t, vars)
+	assert.Empty(t, envs)
+}
+
+func TestAppVarFiles(t *testing.T) {
+	t.Parallel()
+
+	files := []string{"common.tfvars", "/abs/prod.tfvars"}
+	assert.Equal(t, files, appVarFiles("/app", "/app", files))
+	assert.Equal(t, []string{"/app/common.tfvars", "/abs/prod.tfvars"}, appVarFiles("/app", "/app/network", files))
+}
+
+func TestEncryptionOption(t *testing.T) {
+	t.Parallel()
+
+	enc := &config.OpenTofuEncryption{Key: config.OpenTofuEncryptionKey{Passphrase: "correct-horse-battery"}}
+
+	_, err := encryptionOption(provider.EngineOpenTofu, enc)
+	assert.NoError(t, err)
+
+	_, err = encryptionOption(provider.EngineTerraform, enc)
+	assert.Error(t, err, "terraform does not support state encryption")
+
+	_, err = encryptionOption(provider.EngineOpenTofu, &config.OpenTofuEncryption{Key: config.OpenTofuEncryptionKey{Passphrase: "short"}})
+	assert.Error(t, err)
+}
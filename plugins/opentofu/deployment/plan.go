@@ -17,19 +17,17 @@ package deployment
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 
 	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
 )
 
 func (p *Plugin) executePlanStage(ctx context.Context, input *sdk.ExecuteStageInput[config.ApplicationConfigSpec], dts []*sdk.DeployTarget[config.DeployTargetConfig]) sdk.StageStatus {
-	cmd, err := initOpenTofuCommand(ctx, input.Client, input.Request.TargetDeploymentSource, dts[0])
-	if err != nil {
-		return sdk.StageStatusFailure
-	}
-
 	lp := input.Client.LogPersister()
+	ds := input.Request.TargetDeploymentSource
 
 	stageConfig := config.OpenTofuPlanStageOptions{}
 	if err := json.Unmarshal(input.Request.StageConfig, &stageConfig); err != nil {
@@ -37,20 +35,35 @@ func (p *Plugin) executePlanStage(ctx context.Context, input *sdk.ExecuteStageIn
 		return sdk.StageStatusFailure
 	}
 
-	planResult, err := cmd.Plan(ctx, lp)
+	modules, err := rootModules(ds.ApplicationConfig.Spec)
 	if err != nil {
-		lp.Errorf("Failed to plan (%v)", err)
+		lp.Errorf("Failed to resolve modules (%v)", err)
 		return sdk.StageStatusFailure
 	}
 
-	if planResult.NoChanges() {
-		lp.Success("No changes to apply")
-		if stageConfig.ExitOnNoChanges {
-			return sdk.StageStatusExited
+	noChanges := true
+	err = forEachModule(ctx, input.Client, ds, dts[0], modules, false, func(m config.OpenTofuModule, cmd *provider.OpenTofu) error {
+		planResult, err := cmd.Plan(ctx, lp)
+		if err != nil {
+			return fmt.Errorf("failed to plan%s: %w", moduleSuffix(m), err)
+		}
+
+		if planResult.NoChanges() {
+			lp.Successf("No changes to apply%s", moduleSuffix(m))
+			return nil
 		}
-		return sdk.StageStatusSuccess
+
+		noChanges = false
+		lp.Successf("Detected %d import, %d add, %d change, %d destroy%s.", planResult.Imports, planResult.Adds, planResult.Changes, planResult.Destroys, moduleSuffix(m))
+		return nil
+	})
+	if err != nil {
+		lp.Errorf("Failed to plan (%v)", err)
+		return sdk.StageStatusFailure
 	}
 
-	lp.Successf("Detected %d import, %d add, %d change, %d destroy.", planResult.Imports, planResult.Adds, planResult.Changes, planResult.Destroys)
+	if noChanges && stageConfig.ExitOnNoChanges {
+		return sdk.StageStatusExited
+	}
 	return sdk.StageStatusSuccess
 }
@@ -17,6 +17,7 @@ package deployment
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"slices"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
@@ -33,6 +34,10 @@ const (
 	stageApply = "OPENTOFU_APPLY"
 	// OPENTOFU_ROLLBACK stage rollbacks by executing 'tofu apply' for the previous state.
 	stageRollback = "OPENTOFU_ROLLBACK"
+	// OPENTOFU_IMPORT stage imports existing resources by executing 'tofu apply' with "import" blocks.
+	stageImport = "OPENTOFU_IMPORT"
+	// OPENTOFU_ROTATE_ENCRYPTION_KEY stage re-encrypts the state with the current key by executing 'tofu apply -refresh-only'.
+	stageRotateEncryptionKey = "OPENTOFU_ROTATE_ENCRYPTION_KEY"
 )
 
 // Plugin implements sdk.DeploymentPlugin for OpenTofu.
@@ -45,6 +50,8 @@ func (*Plugin) FetchDefinedStages() []string {
 		stagePlan,
 		stageApply,
 		stageRollback,
+		stageImport,
+		stageRotateEncryptionKey,
 	}
 }
 
@@ -94,6 +101,14 @@ func (p *Plugin) ExecuteStage(ctx context.Context, cfg *config.Config, dts []*sd
 		return &sdk.ExecuteStageResponse{
 			Status: p.executeRollbackStage(ctx, input, dts),
 		}, nil
+	case stageImport:
+		return &sdk.ExecuteStageResponse{
+			Status: p.executeImportStage(ctx, input, dts),
+		}, nil
+	case stageRotateEncryptionKey:
+		return &sdk.ExecuteStageResponse{
+			Status: p.executeRotateEncryptionKeyStage(ctx, input, dts),
+		}, nil
 	default:
 		return nil, errors.New("unsupported stage")
 	}
@@ -101,12 +116,27 @@ func (p *Plugin) ExecuteStage(ctx context.Context, cfg *config.Config, dts []*sd
 
 // DetermineVersions determines the versions of artifacts for the deployment.
 func (p *Plugin) DetermineVersions(ctx context.Context, cfg *config.Config, input *sdk.DetermineVersionsInput[config.ApplicationConfigSpec]) (*sdk.DetermineVersionsResponse, error) {
-	files, err := provider.LoadOpenTofuFiles(input.Request.DeploymentSource.ApplicationDirectory)
+	ds := input.Request.DeploymentSource
+	var spec *config.ApplicationConfigSpec
+	if ds.ApplicationConfig != nil {
+		spec = ds.ApplicationConfig.Spec
+	}
+	modules, err := rootModules(spec)
 	if err != nil {
-		input.Logger.Error("failed to load OpenTofu files", zap.Error(err))
+		input.Logger.Error("failed to resolve modules", zap.Error(err))
 		return nil, err
 	}
 
+	files := make([]provider.File, 0)
+	for _, m := range modules {
+		fs, err := provider.LoadOpenTofuFiles(filepath.Join(ds.ApplicationDirectory, m.Dir))
+		if err != nil {
+			input.Logger.Error("failed to load OpenTofu files", zap.String("module", m.Name), zap.Error(err))
+			return nil, err
+		}
+		files = append(files, fs...)
+	}
+
 	versions, err := provider.FindArtifactVersions(files)
 	if err != nil || len(versions) == 0 {
 		input.Logger.Warn("unable to determine target versions", zap.Error(err))
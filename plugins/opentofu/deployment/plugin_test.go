@@ -23,7 +23,7 @@ import (
 
 func Test_FetchDefinedStages(t *testing.T) {
 	plugin := &Plugin{}
-	desiredStages := []string{"OPENTOFU_PLAN", "OPENTOFU_APPLY", "OPENTOFU_ROLLBACK"}
+	desiredStages := []string{"OPENTOFU_PLAN", "OPENTOFU_APPLY", "OPENTOFU_ROLLBACK", "OPENTOFU_IMPORT", "OPENTOFU_ROTATE_ENCRYPTION_KEY"}
 	expectedstages := plugin.FetchDefinedStages()
 
 	assert.Equal(t, desiredStages, expectedstages, "Defined stages should match the expected stages")
@@ -16,10 +16,12 @@ package deployment
 
 import (
 	"context"
+	"slices"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 
 	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
 )
 
 func (p *Plugin) executeRollbackStage(ctx context.Context, input *sdk.ExecuteStageInput[config.ApplicationConfigSpec], dts []*sdk.DeployTarget[config.DeployTargetConfig]) sdk.StageStatus {
@@ -31,17 +33,38 @@ func (p *Plugin) executeRollbackStage(ctx context.Context, input *sdk.ExecuteSta
 		return sdk.StageStatusFailure
 	}
 
-	cmd, err := initOpenTofuCommand(ctx, input.Client, rds, dts[0])
+	modules, err := rootModules(rds.ApplicationConfig.Spec)
 	if err != nil {
+		lp.Errorf("Failed to resolve modules (%v)", err)
 		return sdk.StageStatusFailure
 	}
 
 	lp.Infof("Start rolling back to the state defined at commit %s", rds.CommitHash)
-	if err = cmd.Apply(ctx, lp); err != nil {
-		lp.Errorf("Failed to apply changes (%v)", err)
+
+	// The modules are rolled back in the reverse dependency order so that the downstream modules
+	// stop depending on the resources of the upstream modules before they are rolled back.
+	// They are initialized in the dependency order first to pass the current outputs of the upstream modules.
+	type rollbackModule struct {
+		module config.OpenTofuModule
+		cmd    *provider.OpenTofu
+	}
+	var initialized []rollbackModule
+	err = forEachModule(ctx, input.Client, rds, dts[0], modules, true, func(m config.OpenTofuModule, cmd *provider.OpenTofu) error {
+		initialized = append(initialized, rollbackModule{module: m, cmd: cmd})
+		return nil
+	})
+	if err != nil {
+		lp.Errorf("Failed to initialize modules (%v)", err)
 		return sdk.StageStatusFailure
 	}
 
+	for _, rm := range slices.Backward(initialized) {
+		if err := rm.cmd.Apply(ctx, lp); err != nil {
+			lp.Errorf("Failed to apply changes%s (%v)", moduleSuffix(rm.module), err)
+			return sdk.StageStatusFailure
+		}
+	}
+
 	lp.Success("Successfully rolled back the changes")
 	return sdk.StageStatusSuccess
 }
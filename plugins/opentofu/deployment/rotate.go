@@ -0,0 +1,101 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
+)
+
+// executeRotateEncryptionKeyStage rewrites the state so that it is encrypted by the current key.
+// The state encrypted by the previous key is read by using the fallback key of the deploy target.
+func (p *Plugin) executeRotateEncryptionKeyStage(ctx context.Context, input *sdk.ExecuteStageInput[config.ApplicationConfigSpec], dts []*sdk.DeployTarget[config.DeployTargetConfig]) sdk.StageStatus {
+	lp := input.Client.LogPersister()
+	lp.Info("Starting OpenTofu encryption key rotation stage")
+
+	enc := dts[0].Config.Encryption
+	if enc == nil || enc.FallbackKey == nil {
+		lp.Error("Both encryption.key and encryption.fallbackKey must be configured on the deploy target to rotate the key")
+		return sdk.StageStatusFailure
+	}
+
+	ds := input.Request.TargetDeploymentSource
+	modules, err := rootModules(ds.ApplicationConfig.Spec)
+	if err != nil {
+		lp.Errorf("Failed to resolve modules (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	err = forEachModule(ctx, input.Client, ds, dts[0], modules, true, func(m config.OpenTofuModule, cmd *provider.OpenTofu) error {
+		return reencryptState(ctx, lp, m, cmd)
+	})
+	if err != nil {
+		lp.Errorf("Failed to rotate the encryption key (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	lp.Success("Successfully re-encrypted the state with the current key. The fallback key can be removed from the deploy target now.")
+	return sdk.StageStatusSuccess
+}
+
+// reencryptState rewrites the state of the module so that it is encrypted by the current key.
+func reencryptState(ctx context.Context, lp sdk.StageLogPersister, m config.OpenTofuModule, cmd *provider.OpenTofu) error {
+	before, exists, err := cmd.StateSerial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the state%s: %w", moduleSuffix(m), err)
+	}
+	if !exists {
+		lp.Infof("No state exists%s, nothing to re-encrypt.", moduleSuffix(m))
+		return nil
+	}
+
+	lp.Infof("Start re-encrypting the state%s.", moduleSuffix(m))
+	if err := cmd.Apply(ctx, lp, provider.WithRefreshOnly()); err != nil {
+		return fmt.Errorf("failed to re-encrypt the state%s: %w", moduleSuffix(m), err)
+	}
+	if rewritten, err := stateRewritten(ctx, cmd, before); err != nil || rewritten {
+		return err
+	}
+
+	// The refresh-only apply does not write the state when nothing has drifted,
+	// so the state is pushed back explicitly to get it encrypted by the current key.
+	lp.Infof("The state%s was not rewritten by the apply, writing it back explicitly.", moduleSuffix(m))
+	if err := cmd.RewriteState(ctx, lp); err != nil {
+		return fmt.Errorf("failed to rewrite the state%s: %w", moduleSuffix(m), err)
+	}
+	rewritten, err := stateRewritten(ctx, cmd, before)
+	if err != nil {
+		return err
+	}
+	if !rewritten {
+		return fmt.Errorf("the state%s is still encrypted with the previous key", moduleSuffix(m))
+	}
+	return nil
+}
+
+// stateRewritten reports whether a new state has been written since the state had the given serial.
+// Every written state is encrypted by the current key.
+func stateRewritten(ctx context.Context, cmd *provider.OpenTofu, before int64) (bool, error) {
+	after, _, err := cmd.StateSerial(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the state: %w", err)
+	}
+	return after > before, nil
+}
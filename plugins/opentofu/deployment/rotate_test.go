@@ -0,0 +1,134 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
+)
+
+// writeStateTofu writes a script that keeps the state in a file and records its arguments.
+// Like tofu, apply rejects "-refresh-only" with "-refresh=false", and it writes the state only when drifted is true.
+func writeStateTofu(t *testing.T, drifted bool) (execPath, logPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "calls")
+	statePath := filepath.Join(dir, "state")
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"version": 4, "serial": 1, "lineage": "abc"}`), 0644))
+
+	write := ""
+	if drifted {
+		write = `echo '{"version": 4, "serial": 2, "lineage": "abc"}' > ` + statePath
+	}
+	execPath = filepath.Join(dir, "tofu")
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$1 $2" in
+  "state pull") cat ` + statePath + ` ;;
+  "state push") cp "$3" ` + statePath + ` ;;
+  apply*)
+    case "$*" in
+      *-refresh=false*-refresh-only*) echo "Error: Incompatible refresh options" >&2; exit 1 ;;
+    esac
+    ` + write + ` ;;
+esac
+exit 0
+`
+	require.NoError(t, os.WriteFile(execPath, []byte(script), 0755))
+	return execPath, logPath
+}
+
+func TestReencryptState(t *testing.T) {
+	t.Parallel()
+
+	refresh := false
+	testcases := []struct {
+		name     string
+		flags    config.OpenTofuCommandFlags
+		drifted  bool
+		expected []string
+	}{
+		{
+			name:     "refresh disabled",
+			flags:    config.OpenTofuCommandFlags{Refresh: &refresh, Parallelism: 5},
+			drifted:  true,
+			expected: []string{"-parallelism=5", "-refresh-only"},
+		},
+		{
+			name: "shared and apply flags",
+			flags: config.OpenTofuCommandFlags{
+				Shared: []string{"-lock-timeout=30s"},
+				Apply:  []string{"-lock=true"},
+			},
+			drifted:  true,
+			expected: []string{"-lock-timeout=30s", "-lock=true", "-refresh-only"},
+		},
+		{
+			name: "state written back without drift",
+			flags: config.OpenTofuCommandFlags{
+				Shared: []string{"-lock-timeout=30s"},
+				Apply:  []string{"-lock=true"},
+			},
+			expected: []string{"-lock-timeout=30s", "-lock=true", "-refresh-only"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			execPath, logPath := writeStateTofu(t, tc.drifted)
+			dir := t.TempDir()
+			ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: dir,
+				ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+					Spec: &config.ApplicationConfigSpec{CommandFlags: tc.flags},
+				},
+			}
+			opts, err := commandOptions(ds, &sdk.DeployTarget[config.DeployTargetConfig]{}, config.OpenTofuModule{}, provider.EngineOpenTofu, dir, nil, nil)
+			require.NoError(t, err)
+			cmd := provider.NewOpenTofu(execPath, dir, opts...)
+
+			err = reencryptState(t.Context(), logpersistertest.NewTestLogPersister(t), config.OpenTofuModule{}, cmd)
+			require.NoError(t, err)
+
+			var apply string
+			for _, c := range readCalls(t, logPath) {
+				if strings.HasPrefix(c, "apply ") {
+					apply = c
+				}
+			}
+			require.NotEmpty(t, apply)
+			// Without drift, the refresh-only apply does not write the state so it is pushed back.
+			assert.Equal(t, !tc.drifted, slices.ContainsFunc(readCalls(t, logPath), func(c string) bool { return strings.HasPrefix(c, "state push ") }))
+			args := strings.Fields(apply)
+			assert.NotContains(t, args, "-refresh=false")
+			for _, want := range tc.expected {
+				assert.Contains(t, args, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
+)
+
+const (
+	defaultHealthCheckTimeout  = time.Minute
+	defaultHealthCheckInterval = 10 * time.Second
+	healthCheckRequestTimeout  = 10 * time.Second
+)
+
+// applyWaves applies the changes matched by each wave in order, then applies the remaining changes.
+func applyWaves(ctx context.Context, cmd *provider.OpenTofu, m config.OpenTofuModule, waves []config.OpenTofuApplyWave, lp sdk.StageLogPersister) error {
+	var addresses []string
+	if slices.ContainsFunc(waves, func(w config.OpenTofuApplyWave) bool {
+		return slices.ContainsFunc(w.Targets, func(t string) bool { return strings.Contains(t, "*") })
+	}) {
+		var err error
+		if addresses, err = cmd.PlannedAddresses(ctx); err != nil {
+			return fmt.Errorf("failed to resolve target patterns%s: %w", moduleSuffix(m), err)
+		}
+	}
+
+	completed := make([]string, 0, len(waves))
+	for i, w := range waves {
+		name := w.WaveName(i)
+		targets := provider.ExpandTargets(w.Targets, addresses)
+		if len(targets) == 0 {
+			lp.Infof("No planned changes matched wave %q%s, skipped it", name, moduleSuffix(m))
+			completed = append(completed, name)
+			continue
+		}
+
+		lp.Infof("Start applying wave %q%s with targets %s", name, moduleSuffix(m), strings.Join(targets, ", "))
+		if err := cmd.Apply(ctx, lp, provider.WithTargets(targets...)); err != nil {
+			return fmt.Errorf("failed to apply wave %q%s, completed waves: %v: %w", name, moduleSuffix(m), completed, err)
+		}
+		if err := waitAfterWave(ctx, w, lp); err != nil {
+			return fmt.Errorf("failed to check wave %q%s, completed waves: %v: %w", name, moduleSuffix(m), completed, err)
+		}
+		completed = append(completed, name)
+		lp.Successf("Completed wave %q%s", name, moduleSuffix(m))
+	}
+
+	lp.Infof("Start applying the remaining changes%s.", moduleSuffix(m))
+	if err := cmd.Apply(ctx, lp); err != nil {
+		return fmt.Errorf("failed to apply the remaining changes%s, completed waves: %v: %w", moduleSuffix(m), completed, err)
+	}
+	return nil
+}
+
+func waitAfterWave(ctx context.Context, w config.OpenTofuApplyWave, lp sdk.StageLogPersister) error {
+	if w.Wait > 0 {
+		d := time.Duration(w.Wait)
+		lp.Infof("Waiting %s before the next wave", d)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	if w.HealthCheck != nil {
+		return probeHealth(ctx, http.DefaultClient, *w.HealthCheck, lp)
+	}
+	return nil
+}
+
+// probeHealth sends GET requests to the health check URL until it returns the expected status or the timeout expires.
+func probeHealth(ctx context.Context, client *http.Client, hc config.OpenTofuHealthCheck, lp sdk.StageLogPersister) error {
+	timeout := time.Duration(hc.Timeout)
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	interval := time.Duration(hc.Interval)
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lp.Infof("Start probing %s", hc.URL)
+	for {
+		status, err := probeOnce(ctx, client, hc.URL)
+		switch {
+		case err != nil:
+			lp.Infof("Health check failed (%v)", err)
+		case hc.ExpectedStatus == 0 && status >= 200 && status < 300, status == hc.ExpectedStatus:
+			lp.Successf("Health check passed with status %d", status)
+			return nil
+		default:
+			lp.Infof("Health check returned unexpected status %d", status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("health check did not pass within %s", timeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func probeOnce(ctx context.Context, client *http.Client, url string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
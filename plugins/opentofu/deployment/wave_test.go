@@ -0,0 +1,117 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/config"
+	"github.com/pipe-cd/community-plugins/plugins/opentofu/provider"
+)
+
+// writeRecordingTofu writes a script that records its arguments and fails when they contain failOn.
+func writeRecordingTofu(t *testing.T, failOn string) (execPath, logPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "calls")
+	execPath = filepath.Join(dir, "tofu")
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$*" in
+  *'` + failOn + `'*) [ -n '` + failOn + `' ] && exit 1 ;;
+esac
+exit 0
+`
+	require.NoError(t, os.WriteFile(execPath, []byte(script), 0755))
+	return execPath, logPath
+}
+
+func readCalls(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestApplyWaves(t *testing.T) {
+	t.Parallel()
+
+	waves := []config.OpenTofuApplyWave{
+		{Name: "canary", Targets: []string{`module.region["us-east-1"]`}},
+		{Targets: []string{`module.region["us-west-2"]`, `module.region["eu-west-1"]`}},
+	}
+
+	t.Run("all waves succeed", func(t *testing.T) {
+		t.Parallel()
+		execPath, logPath := writeRecordingTofu(t, "")
+		cmd := provider.NewOpenTofu(execPath, t.TempDir())
+
+		err := applyWaves(t.Context(), cmd, config.OpenTofuModule{}, waves, logpersistertest.NewTestLogPersister(t))
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`apply -auto-approve -input=false -target=module.region["us-east-1"]`,
+			`apply -auto-approve -input=false -target=module.region["eu-west-1"] -target=module.region["us-west-2"]`,
+			`apply -auto-approve -input=false`,
+		}, readCalls(t, logPath))
+	})
+
+	t.Run("stop at the failed wave", func(t *testing.T) {
+		t.Parallel()
+		execPath, logPath := writeRecordingTofu(t, "us-west-2")
+		cmd := provider.NewOpenTofu(execPath, t.TempDir())
+
+		err := applyWaves(t.Context(), cmd, config.OpenTofuModule{}, waves, logpersistertest.NewTestLogPersister(t))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `failed to apply wave "wave-1", completed waves: [canary]`)
+		assert.Len(t, readCalls(t, logPath), 2)
+	})
+}
+
+func TestProbeHealth(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	hc := config.OpenTofuHealthCheck{
+		URL:      server.URL,
+		Timeout:  config.Duration(5 * time.Second),
+		Interval: config.Duration(10 * time.Millisecond),
+	}
+	require.NoError(t, probeHealth(t.Context(), server.Client(), hc, logpersistertest.NewTestLogPersister(t)))
+	assert.Equal(t, int32(3), calls.Load())
+
+	hc.ExpectedStatus = http.StatusOK
+	hc.Timeout = config.Duration(50 * time.Millisecond)
+	assert.Error(t, probeHealth(t.Context(), server.Client(), hc, logpersistertest.NewTestLogPersister(t)))
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ParseAddress parses the given resource address such as `module.foo["bar"].aws_instance.example`.
+func ParseAddress(address string) (hcl.Traversal, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(address), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid resource address %q: %w", address, diags)
+	}
+	return traversal, nil
+}
+
+// addressPrefixes returns the address and all of its parents usable as targets,
+// e.g. `module.a`, `module.a["x"]` and `module.a["x"].aws_instance.b` for `module.a["x"].aws_instance.b`.
+func addressPrefixes(address string) []string {
+	traversal, err := ParseAddress(address)
+	if err != nil {
+		return []string{address}
+	}
+	prefixes := make([]string, 0, len(traversal))
+	names := 0
+	for _, step := range traversal {
+		end := step.SourceRange().End.Byte
+		if end > len(address) {
+			break
+		}
+		switch step.(type) {
+		case hcl.TraverseIndex:
+		default:
+			// Names come in pairs such as "module.<name>" or "<type>.<name>".
+			names++
+			if names%2 != 0 {
+				continue
+			}
+		}
+		prefixes = append(prefixes, address[:end])
+	}
+	return prefixes
+}
+
+func compileAddressPattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// ExpandTargets resolves the given target address patterns to addresses usable with "-target" flag.
+// Patterns without "*" are returned as they are. Patterns with "*" are matched against
+// the given addresses and their parents, and the shortest matched ones are returned.
+func ExpandTargets(patterns []string, addresses []string) []string {
+	targets := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "*") {
+			targets = append(targets, pattern)
+			continue
+		}
+		re := compileAddressPattern(pattern)
+		for _, addr := range addresses {
+			for _, prefix := range addressPrefixes(addr) {
+				if re.MatchString(prefix) {
+					targets = append(targets, prefix)
+					break
+				}
+			}
+		}
+	}
+	slices.Sort(targets)
+	return slices.Compact(targets)
+}
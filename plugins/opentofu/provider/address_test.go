@@ -0,0 +1,95 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressPrefixes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		[]string{`module.region`, `module.region["us-east-1"]`, `module.region["us-east-1"].aws_instance.web`, `module.region["us-east-1"].aws_instance.web[0]`},
+		addressPrefixes(`module.region["us-east-1"].aws_instance.web[0]`),
+	)
+	assert.Equal(t, []string{"aws_instance.web"}, addressPrefixes("aws_instance.web"))
+}
+
+func TestExpandTargets(t *testing.T) {
+	t.Parallel()
+
+	addresses := []string{
+		`module.region["us-east-1"].aws_instance.web[0]`,
+		`module.region["us-east-1"].aws_instance.web[1]`,
+		`module.region["us-west-2"].aws_instance.web[0]`,
+		`module.region["eu-west-1"].aws_instance.web[0]`,
+		`aws_route53_record.www`,
+	}
+
+	testcases := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{
+			name:     "exact address is kept as is",
+			patterns: []string{`module.region["ap-northeast-1"]`},
+			expected: []string{`module.region["ap-northeast-1"]`},
+		},
+		{
+			name:     "wildcard in index key",
+			patterns: []string{`module.region["us-*"]`},
+			expected: []string{`module.region["us-east-1"]`, `module.region["us-west-2"]`},
+		},
+		{
+			name:     "wildcard in resource name",
+			patterns: []string{`aws_route53_record.*`},
+			expected: []string{`aws_route53_record.www`},
+		},
+		{
+			name:     "no match",
+			patterns: []string{`module.zone["*"]`},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, ExpandTargets(tc.patterns, addresses))
+		})
+	}
+}
+
+func TestParsePlannedAddresses(t *testing.T) {
+	t.Parallel()
+
+	input := `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_instance.a", "change": {"actions": ["create"]}},
+    {"address": "aws_instance.b", "change": {"actions": ["no-op"]}},
+    {"address": "aws_instance.c", "change": {"actions": ["delete", "create"]}},
+    {"address": "data.aws_ami.d", "change": {"actions": ["read"]}}
+  ]
+}`
+	got, err := parsePlannedAddresses([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_instance.a", "aws_instance.c"}, got)
+}
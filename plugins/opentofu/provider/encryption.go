@@ -0,0 +1,90 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"errors"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// encryptionEnv is the environment variable OpenTofu reads the encryption configuration from.
+const encryptionEnv = "TF_ENCRYPTION"
+
+// minPassphraseLength is the minimum passphrase length accepted by the pbkdf2 key provider.
+const minPassphraseLength = 16
+
+// EncryptionKey represents a passphrase based key used for the client-side state encryption.
+type EncryptionKey struct {
+	Passphrase string
+}
+
+// WithEncryption enables the client-side state and plan encryption with the given key.
+// The fallback key is used to decrypt the files which are not encrypted by the key yet.
+// The configuration is passed through the environment variable so that the passphrase is not shown in the command.
+func WithEncryption(key EncryptionKey, fallback *EncryptionKey) Option {
+	return func(opts *options) {
+		opts.sharedEnvs = append(opts.sharedEnvs, encryptionEnv+"="+RenderEncryptionConfig(key, fallback))
+	}
+}
+
+// Validate checks the key is acceptable by the key provider.
+func (k EncryptionKey) Validate() error {
+	if len(k.Passphrase) < minPassphraseLength {
+		return errors.New("passphrase must be at least 16 characters long")
+	}
+	return nil
+}
+
+// RenderEncryptionConfig renders the encryption configuration for the given keys.
+func RenderEncryptionConfig(key EncryptionKey, fallback *EncryptionKey) string {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+
+	appendKey := func(name string, k EncryptionKey) {
+		kp := body.AppendNewBlock("key_provider", []string{"pbkdf2", name}).Body()
+		kp.SetAttributeValue("passphrase", cty.StringVal(k.Passphrase))
+		m := body.AppendNewBlock("method", []string{"aes_gcm", name}).Body()
+		m.SetAttributeTraversal("keys", hcl.Traversal{
+			hcl.TraverseRoot{Name: "key_provider"},
+			hcl.TraverseAttr{Name: "pbkdf2"},
+			hcl.TraverseAttr{Name: name},
+		})
+	}
+	methodTraversal := func(name string) hcl.Traversal {
+		return hcl.Traversal{
+			hcl.TraverseRoot{Name: "method"},
+			hcl.TraverseAttr{Name: "aes_gcm"},
+			hcl.TraverseAttr{Name: name},
+		}
+	}
+
+	appendKey("primary", key)
+	if fallback != nil {
+		appendKey("fallback", *fallback)
+	}
+
+	for _, target := range []string{"state", "plan"} {
+		b := body.AppendNewBlock(target, nil).Body()
+		b.SetAttributeTraversal("method", methodTraversal("primary"))
+		if fallback != nil {
+			b.AppendNewBlock("fallback", nil).Body().SetAttributeTraversal("method", methodTraversal("fallback"))
+		}
+	}
+
+	return string(f.Bytes())
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEncryptionConfig(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		key      EncryptionKey
+		fallback *EncryptionKey
+		expected string
+	}{
+		{
+			name: "without fallback",
+			key:  EncryptionKey{Passphrase: "correct-horse-battery"},
+			expected: `key_provider "pbkdf2" "primary" {
+  passphrase = "correct-horse-battery"
+}
+method "aes_gcm" "primary" {
+  keys = key_provider.pbkdf2.primary
+}
+state {
+  method = method.aes_gcm.primary
+}
+plan {
+  method = method.aes_gcm.primary
+}
+`,
+		},
+		{
+			name:     "with fallback",
+			key:      EncryptionKey{Passphrase: "correct-horse-battery"},
+			fallback: &EncryptionKey{Passphrase: "previous-\"passphrase\""},
+			expected: `key_provider "pbkdf2" "primary" {
+  passphrase = "correct-horse-battery"
+}
+method "aes_gcm" "primary" {
+  keys = key_provider.pbkdf2.primary
+}
+key_provider "pbkdf2" "fallback" {
+  passphrase = "previous-\"passphrase\""
+}
+method "aes_gcm" "fallback" {
+  keys = key_provider.pbkdf2.fallback
+}
+state {
+  method = method.aes_gcm.primary
+  fallback {
+    method = method.aes_gcm.fallback
+  }
+}
+plan {
+  method = method.aes_gcm.primary
+  fallback {
+    method = method.aes_gcm.fallback
+  }
+}
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, RenderEncryptionConfig(tc.key, tc.fallback))
+		})
+	}
+}
+
+func TestWithEncryption(t *testing.T) {
+	t.Parallel()
+
+	tofu := NewOpenTofu("tofu", ".", WithEncryption(EncryptionKey{Passphrase: "correct-horse-battery"}, nil))
+
+	assert.Len(t, tofu.options.sharedEnvs, 1)
+	assert.True(t, strings.HasPrefix(tofu.options.sharedEnvs[0], "TF_ENCRYPTION="))
+	assert.NotContains(t, strings.Join(tofu.makeCommonCommandArgs(), " "), "correct-horse-battery")
+}
+
+func TestEncryptionKey_Validate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, EncryptionKey{Passphrase: "0123456789abcdef"}.Validate())
+	assert.Error(t, EncryptionKey{Passphrase: "too-short"}.Validate())
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ImportFileName is the name of the file generated to hold "import" blocks.
+const ImportFileName = "pipecd_imports.tf"
+
+// Import represents a pair of resource address and ID to be imported.
+type Import struct {
+	Address string
+	ID      string
+}
+
+// RenderImportBlocks renders the given imports as OpenTofu "import" blocks.
+func RenderImportBlocks(imports []Import) ([]byte, error) {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for i, imp := range imports {
+		traversal, err := ParseAddress(imp.Address)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			body.AppendNewline()
+		}
+		b := body.AppendNewBlock("import", nil).Body()
+		b.SetAttributeTraversal("to", traversal)
+		b.SetAttributeValue("id", cty.StringVal(imp.ID))
+	}
+	return f.Bytes(), nil
+}
+
+// WriteImportFile writes the "import" blocks for the given imports into the given dir
+// and returns the path to the written file.
+func WriteImportFile(dir string, imports []Import) (string, error) {
+	data, err := RenderImportBlocks(imports)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, ImportFileName)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists in the application directory", ImportFileName)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write import file: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderImportBlocks(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		imports     []Import
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "single resource",
+			imports: []Import{
+				{Address: "aws_instance.example", ID: "i-abc123"},
+			},
+			expected: `import {
+  to = aws_instance.example
+  id = "i-abc123"
+}
+`,
+		},
+		{
+			name: "module instance with index key",
+			imports: []Import{
+				{Address: `module.network["us-east-1"].aws_vpc.main`, ID: "vpc-123"},
+				{Address: "aws_s3_bucket.logs[0]", ID: "logs-${env}"},
+			},
+			expected: `import {
+  to = module.network["us-east-1"].aws_vpc.main
+  id = "vpc-123"
+}
+
+import {
+  to = aws_s3_bucket.logs[0]
+  id = "logs-$${env}"
+}
+`,
+		},
+		{
+			name: "invalid address",
+			imports: []Import{
+				{Address: "aws_instance.", ID: "i-abc123"},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := RenderImportBlocks(tc.imports)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+func TestWriteImportFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	imports := []Import{{Address: "aws_instance.example", ID: "i-abc123"}}
+
+	path, err := WriteImportFile(dir, imports)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ImportFileName), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `id = "i-abc123"`)
+
+	_, err = WriteImportFile(dir, imports)
+	assert.Error(t, err, "an existing import file must not be overwritten")
+}
+
+func TestMakeCommandOptionArgs(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, makeCommandOptionArgs(nil))
+	assert.Equal(t,
+		[]string{"-target=aws_instance.a", `-target=module.b["x"]`},
+		makeCommandOptionArgs([]CommandOption{WithTargets("aws_instance.a"), WithTargets(`module.b["x"]`)}),
+	)
+}
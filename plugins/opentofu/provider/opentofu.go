@@ -18,16 +18,27 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
+// Engine represents the command line tool compatible with OpenTofu.
+type Engine string
+
+const (
+	EngineOpenTofu  Engine = "tofu"
+	EngineTerraform Engine = "terraform"
+)
+
 type options struct {
+	engine   Engine
 	noColor  bool
 	vars     []string
 	varFiles []string
@@ -45,6 +56,13 @@ type options struct {
 
 type Option func(*options)
 
+// WithEngine sets the engine whose command is executed. The default is EngineOpenTofu.
+func WithEngine(engine Engine) Option {
+	return func(opts *options) {
+		opts.engine = engine
+	}
+}
+
 func WithoutColor() Option {
 	return func(opts *options) {
 		opts.noColor = true
@@ -81,6 +99,59 @@ func WithAdditionalEnvs(shared, init, plan, apply []string) Option {
 	}
 }
 
+type commandOptions struct {
+	targets     []string
+	refreshOnly bool
+}
+
+// CommandOption configures a single plan or apply execution.
+type CommandOption func(*commandOptions)
+
+// WithTargets limits the command to the given resource addresses by using "-target" flags.
+func WithTargets(targets ...string) CommandOption {
+	return func(opts *commandOptions) {
+		opts.targets = append(opts.targets, targets...)
+	}
+}
+
+// WithRefreshOnly only updates the state to match the remote objects without changing them.
+func WithRefreshOnly() CommandOption {
+	return func(opts *commandOptions) {
+		opts.refreshOnly = true
+	}
+}
+
+func newCommandOptions(opts []CommandOption) commandOptions {
+	o := commandOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func makeCommandOptionArgs(opts []CommandOption) (args []string) {
+	o := newCommandOptions(opts)
+	if o.refreshOnly {
+		args = append(args, "-refresh-only")
+	}
+	for _, t := range o.targets {
+		args = append(args, fmt.Sprintf("-target=%s", t))
+	}
+	return
+}
+
+// withoutRefreshFlags removes the "-refresh" flags given by users from args
+// when the command runs with "-refresh-only", which cannot be combined with "-refresh=false".
+func withoutRefreshFlags(args []string, opts []CommandOption) []string {
+	if !newCommandOptions(opts).refreshOnly {
+		return args
+	}
+	return slices.DeleteFunc(args, func(arg string) bool {
+		name, _, _ := strings.Cut(arg, "=")
+		return name == "-refresh" || name == "--refresh"
+	})
+}
+
 type OpenTofu struct {
 	execPath string
 	dir      string
@@ -89,7 +160,9 @@ type OpenTofu struct {
 }
 
 func NewOpenTofu(execPath, dir string, opts ...Option) *OpenTofu {
-	opt := options{}
+	opt := options{
+		engine: EngineOpenTofu,
+	}
 	for _, o := range opts {
 		o(&opt)
 	}
@@ -131,7 +204,7 @@ func (t *OpenTofu) Init(ctx context.Context, w io.Writer) error {
 	env = append(env, t.options.initEnvs...)
 	cmd.Env = env
 
-	io.WriteString(w, fmt.Sprintf("tofu %s", strings.Join(args, " ")))
+	io.WriteString(w, fmt.Sprintf("%s %s", t.options.engine, strings.Join(args, " ")))
 	return cmd.Run()
 }
 
@@ -153,6 +226,157 @@ func (t *OpenTofu) SelectWorkspace(ctx context.Context, workspace string) error
 	return nil
 }
 
+// OutputValue is the value of an output of a module.
+type OutputValue struct {
+	// Value is the string value, or the JSON representation for the other types.
+	Value string
+	// Sensitive is true when the output is marked as sensitive and must not be logged.
+	Sensitive bool
+}
+
+// Output returns the outputs of the root module.
+// The values are formatted so that they can be passed to other modules with "-var" flag.
+func (t *OpenTofu) Output(ctx context.Context) (map[string]OutputValue, error) {
+	args := []string{
+		"output",
+		"-json",
+	}
+	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd.Dir = t.dir
+	cmd.Env = append(os.Environ(), t.options.sharedEnvs...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs: %s (%w)", stderr.String(), err)
+	}
+
+	return parseOutputs(out)
+}
+
+func parseOutputs(out []byte) (map[string]OutputValue, error) {
+	var outputs map[string]struct {
+		Sensitive bool            `json:"sensitive"`
+		Value     json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(out, &outputs); err != nil {
+		return nil, fmt.Errorf("unable to parse outputs: %w", err)
+	}
+
+	values := make(map[string]OutputValue, len(outputs))
+	for name, o := range outputs {
+		// String values are passed as is, others are passed as JSON which is also a valid HCL expression.
+		v := OutputValue{Value: string(o.Value), Sensitive: o.Sensitive}
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			v.Value = s
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// StateSerial returns the serial of the current state.
+// The returned exists is false when no state has been written yet.
+func (t *OpenTofu) StateSerial(ctx context.Context) (serial int64, exists bool, err error) {
+	state, err := t.pullState(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(bytes.TrimSpace(state)) == 0 {
+		return 0, false, nil
+	}
+	serial, err = parseStateSerial(state)
+	return serial, err == nil, err
+}
+
+// RewriteState writes the current state back with an incremented serial.
+// This forces the state to be encrypted by the current key even when nothing has changed.
+func (t *OpenTofu) RewriteState(ctx context.Context, w io.Writer) error {
+	state, err := t.pullState(ctx)
+	if err != nil {
+		return err
+	}
+	state, err = bumpStateSerial(state)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "pipecd-state-*.tfstate")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary state file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(state); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the temporary state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write the temporary state file: %w", err)
+	}
+
+	args := []string{
+		"state",
+		"push",
+		f.Name(),
+	}
+	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd.Dir = t.dir
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.Env = append(os.Environ(), t.options.sharedEnvs...)
+
+	io.WriteString(w, fmt.Sprintf("%s state push", t.options.engine))
+	return cmd.Run()
+}
+
+func (t *OpenTofu) pullState(ctx context.Context) ([]byte, error) {
+	args := []string{
+		"state",
+		"pull",
+	}
+	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd.Dir = t.dir
+	cmd.Env = append(os.Environ(), t.options.sharedEnvs...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull the state: %s (%w)", stderr.String(), err)
+	}
+	return out, nil
+}
+
+func parseStateSerial(state []byte) (int64, error) {
+	var s struct {
+		Serial *int64 `json:"serial"`
+	}
+	if err := json.Unmarshal(state, &s); err != nil {
+		return 0, fmt.Errorf("unable to parse state: %w", err)
+	}
+	if s.Serial == nil {
+		return 0, fmt.Errorf("unable to parse state: serial is missing")
+	}
+	return *s.Serial, nil
+}
+
+func bumpStateSerial(state []byte) ([]byte, error) {
+	serial, err := parseStateSerial(state)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(state, &fields); err != nil {
+		return nil, fmt.Errorf("unable to parse state: %w", err)
+	}
+	fields["serial"] = json.RawMessage(strconv.FormatInt(serial+1, 10))
+	return json.MarshalIndent(fields, "", "  ")
+}
+
 type PlanResult struct {
 	Adds            int
 	Changes         int
@@ -167,12 +391,23 @@ func (r PlanResult) NoChanges() bool {
 	return r.Adds == 0 && r.Changes == 0 && r.Destroys == 0 && r.Imports == 0 && !r.HasStateChanges
 }
 
+// diffStarts are the lines printed right before the diff by each engine.
+var diffStarts = []string{
+	"OpenTofu will perform the following actions:",
+	"Terraform will perform the following actions:",
+}
+
 func (r PlanResult) Render() (string, error) {
-	tofuDiffStart := "OpenTofu will perform the following actions:"
-	if !strings.Contains(r.PlanOutput, tofuDiffStart) {
+	startIndex := -1
+	for _, tofuDiffStart := range diffStarts {
+		if i := strings.Index(r.PlanOutput, tofuDiffStart); i >= 0 {
+			startIndex = i + len(tofuDiffStart)
+			break
+		}
+	}
+	if startIndex < 0 {
 		return "", nil
 	}
-	startIndex := strings.Index(r.PlanOutput, tofuDiffStart) + len(tofuDiffStart)
 
 	tofuDiffEnd := fmt.Sprintf("Plan: %d to import, %d to add, %d to change, %d to destroy.", r.Imports, r.Adds, r.Changes, r.Destroys)
 	endIndex := strings.Index(r.PlanOutput, tofuDiffEnd) + len(tofuDiffEnd)
@@ -182,7 +417,7 @@ func (r PlanResult) Render() (string, error) {
 	}
 
 	if endIndex < startIndex {
-		return "", fmt.Errorf("unable to parse OpenTofu plan result")
+		return "", fmt.Errorf("unable to parse plan result")
 	}
 
 	out := r.PlanOutput[startIndex:endIndex]
@@ -273,7 +508,7 @@ func GetExitCode(err error) int {
 	return 1
 }
 
-func (t *OpenTofu) Plan(ctx context.Context, w io.Writer) (PlanResult, error) {
+func (t *OpenTofu) Plan(ctx context.Context, w io.Writer, opts ...CommandOption) (PlanResult, error) {
 	args := []string{
 		"plan",
 		"-lock=false",
@@ -281,6 +516,8 @@ func (t *OpenTofu) Plan(ctx context.Context, w io.Writer) (PlanResult, error) {
 	}
 	args = append(args, t.makeCommonCommandArgs()...)
 	args = append(args, t.options.planFlags...)
+	args = withoutRefreshFlags(args, opts)
+	args = append(args, makeCommandOptionArgs(opts)...)
 
 	var buf bytes.Buffer
 	stdout := io.MultiWriter(w, &buf)
@@ -294,7 +531,7 @@ func (t *OpenTofu) Plan(ctx context.Context, w io.Writer) (PlanResult, error) {
 	env = append(env, t.options.planEnvs...)
 	cmd.Env = env
 
-	io.WriteString(w, fmt.Sprintf("tofu %s", strings.Join(args, " ")))
+	io.WriteString(w, fmt.Sprintf("%s %s", t.options.engine, strings.Join(args, " ")))
 	err := cmd.Run()
 	switch GetExitCode(err) {
 	case 0:
@@ -306,6 +543,71 @@ func (t *OpenTofu) Plan(ctx context.Context, w io.Writer) (PlanResult, error) {
 	}
 }
 
+// PlannedAddresses returns the addresses of the resources that would be changed by apply.
+func (t *OpenTofu) PlannedAddresses(ctx context.Context) ([]string, error) {
+	f, err := os.CreateTemp("", "pipecd-*.tfplan")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan file: %w", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	args := []string{
+		"plan",
+		"-lock=false",
+		"-input=false",
+		fmt.Sprintf("-out=%s", f.Name()),
+	}
+	args = append(args, t.makeCommonCommandArgs()...)
+	args = append(args, t.options.planFlags...)
+
+	cmd := exec.CommandContext(ctx, t.execPath, args...)
+	cmd.Dir = t.dir
+	env := append(os.Environ(), t.options.sharedEnvs...)
+	env = append(env, t.options.planEnvs...)
+	cmd.Env = env
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to plan: %s (%w)", string(out), err)
+	}
+
+	cmd = exec.CommandContext(ctx, t.execPath, "show", "-json", f.Name())
+	cmd.Dir = t.dir
+	cmd.Env = append(os.Environ(), t.options.sharedEnvs...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to show plan: %s (%w)", stderr.String(), err)
+	}
+
+	return parsePlannedAddresses(out)
+}
+
+func parsePlannedAddresses(out []byte) ([]string, error) {
+	var plan struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(out, &plan); err != nil {
+		return nil, fmt.Errorf("unable to parse plan: %w", err)
+	}
+
+	addresses := make([]string, 0, len(plan.ResourceChanges))
+	for _, rc := range plan.ResourceChanges {
+		if len(rc.Change.Actions) == 1 && (rc.Change.Actions[0] == "no-op" || rc.Change.Actions[0] == "read") {
+			continue
+		}
+		addresses = append(addresses, rc.Address)
+	}
+	return addresses, nil
+}
+
 func (t *OpenTofu) makeCommonCommandArgs() (args []string) {
 	if t.options.noColor {
 		args = append(args, "-no-color")
@@ -388,7 +690,7 @@ func parsePlanResult(out string, ansiIncluded bool) (PlanResult, error) {
 	return PlanResult{}, fmt.Errorf("unable to parse plan output")
 }
 
-func (t *OpenTofu) Apply(ctx context.Context, w io.Writer) error {
+func (t *OpenTofu) Apply(ctx context.Context, w io.Writer, opts ...CommandOption) error {
 	args := []string{
 		"apply",
 		"-auto-approve",
@@ -396,6 +698,8 @@ func (t *OpenTofu) Apply(ctx context.Context, w io.Writer) error {
 	}
 	args = append(args, t.makeCommonCommandArgs()...)
 	args = append(args, t.options.applyFlags...)
+	args = withoutRefreshFlags(args, opts)
+	args = append(args, makeCommandOptionArgs(opts)...)
 
 	cmd := exec.CommandContext(ctx, t.execPath, args...)
 	cmd.Dir = t.dir
@@ -406,6 +710,6 @@ func (t *OpenTofu) Apply(ctx context.Context, w io.Writer) error {
 	env = append(env, t.options.applyEnvs...)
 	cmd.Env = env
 
-	io.WriteString(w, fmt.Sprintf("tofu %s", strings.Join(args, " ")))
+	io.WriteString(w, fmt.Sprintf("%s %s", t.options.engine, strings.Join(args, " ")))
 	return cmd.Run()
 }
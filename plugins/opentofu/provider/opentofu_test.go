@@ -15,6 +15,7 @@
 package provider
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -237,6 +238,29 @@ Plan: 1 to import, 2 to add, 3 to change, 4 to destroy.
 +       id    = "foo"
     }
 Plan: 1 to import, 2 to add, 3 to change, 4 to destroy.
+`,
+			expectedErr: false,
+		},
+		{
+			name: "terraform",
+			planResult: &PlanResult{
+				Adds: 1,
+				PlanOutput: `
+Terraform used the selected providers to generate the following execution plan. Resource actions are indicated with the following symbols:
+  + create
+
+Terraform will perform the following actions:
+  + resource "test-add" "test" {
+      + id    = (known after apply)
+    }
+
+Plan: 1 to add, 0 to change, 0 to destroy.
+`,
+			},
+			expected: `    resource "test-add" "test" {
++       id    = (known after apply)
+    }
+Plan: 1 to add, 0 to change, 0 to destroy.
 `,
 			expectedErr: false,
 		},
@@ -300,3 +324,58 @@ guarantee to take exactly these actions if you run "opentofu apply" now.`,
 		})
 	}
 }
+
+func TestParseOutputs(t *testing.T) {
+	t.Parallel()
+
+	input := `{
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"},
+  "subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-1", "subnet-2"]},
+  "replicas": {"sensitive": false, "type": "number", "value": 3},
+  "db_password": {"sensitive": true, "type": "string", "value": "s3cret"}
+}`
+	got, err := parseOutputs([]byte(input))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]OutputValue{
+		"vpc_id":      {Value: "vpc-123"},
+		"subnet_ids":  {Value: `["subnet-1", "subnet-2"]`},
+		"replicas":    {Value: "3"},
+		"db_password": {Value: "s3cret", Sensitive: true},
+	}, got)
+
+	_, err = parseOutputs([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestParseStateSerial(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseStateSerial([]byte(`{"version": 4, "serial": 12, "lineage": "abc"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), got)
+
+	_, err = parseStateSerial([]byte(`{"version": 4}`))
+	assert.Error(t, err)
+
+	_, err = parseStateSerial([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestBumpStateSerial(t *testing.T) {
+	t.Parallel()
+
+	got, err := bumpStateSerial([]byte(`{"version": 4, "serial": 12, "lineage": "abc", "resources": [{"type": "null_resource"}]}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version": 4, "serial": 13, "lineage": "abc", "resources": [{"type": "null_resource"}]}`, string(got))
+
+	_, err = bumpStateSerial([]byte(`{"version": 4}`))
+	assert.Error(t, err)
+}
+
+func TestWithoutRefreshFlags(t *testing.T) {
+	t.Parallel()
+
+	args := []string{"-parallelism=5", "-refresh=false", "--refresh=false", "-compact-warnings"}
+	assert.Equal(t, []string{"-parallelism=5", "-compact-warnings"}, withoutRefreshFlags(slices.Clone(args), []CommandOption{WithRefreshOnly()}))
+	assert.Equal(t, args, withoutRefreshFlags(slices.Clone(args), []CommandOption{WithTargets("null_resource.a")}))
+}
@@ -20,7 +20,8 @@ import (
 )
 
 const (
-	defaultOpenTofuVersion = "1.9.1"
+	defaultOpenTofuVersion  = "1.9.1"
+	defaultTerraformVersion = "1.5.7"
 )
 
 type client interface {
@@ -44,3 +45,9 @@ type Registry struct {
 func (r *Registry) OpenTofu(ctx context.Context, version string) (string, error) {
 	return r.client.InstallTool(ctx, "OpenTofu", cmp.Or(version, defaultOpenTofuVersion), OpenTofuInstallScript)
 }
+
+// Terraform installs the Terraform tool with the given version and return the path to the installed binary.
+// If the version is empty, the default version will be used.
+func (r *Registry) Terraform(ctx context.Context, version string) (string, error) {
+	return r.client.InstallTool(ctx, "Terraform", cmp.Or(version, defaultTerraformVersion), TerraformInstallScript)
+}
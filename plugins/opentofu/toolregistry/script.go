@@ -20,3 +20,10 @@ curl -L https://github.com/opentofu/opentofu/releases/download/v{{ .Version }}/t
 unzip tofu_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip
 mv tofu {{ .OutPath }}
 `
+
+var TerraformInstallScript = `
+cd {{ .TmpDir }}
+curl -L https://releases.hashicorp.com/terraform/{{ .Version }}/terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip -o terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip
+unzip terraform_{{ .Version }}_{{ .Os }}_{{ .Arch }}.zip
+mv terraform {{ .OutPath }}
+`
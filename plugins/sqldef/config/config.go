@@ -1,5 +1,16 @@
 package config
 
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
 type DBType string
 
 const (
@@ -9,17 +20,481 @@ const (
 	DBTypeMSSQL    DBType = "mssql"
 )
 
+// DestructiveChangePolicy is the policy for destructive changes such as DROP TABLE found in the dry run.
+type DestructiveChangePolicy string
+
+const (
+	// DestructiveChangePolicyAllow applies destructive changes as they are.
+	DestructiveChangePolicyAllow DestructiveChangePolicy = "allow"
+	// DestructiveChangePolicyDeny fails the apply stage when it finds destructive changes.
+	DestructiveChangePolicyDeny DestructiveChangePolicy = "deny"
+	// DestructiveChangePolicyApproval waits for a manual approval before applying destructive changes.
+	DestructiveChangePolicyApproval DestructiveChangePolicy = "approval"
+)
+
 type Config struct{}
 
 type DeployTargetConfig struct {
 	DbType DBType `json:"db_type"`
 	// DB connection info
+	// For SQL Server named instances, set the port the instance listens on
+	// because mssqldef does not resolve instance names.
+	// Prefer password_file or password_env to password to avoid writing the password in the piped config.
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Host     string `json:"host"`
 	Port     string `json:"port"`
 	DBName   string `json:"db_name"`
+	// The path to the file containing the password.
+	PasswordFile string `json:"password_file,omitempty"`
+	// The name of the environment variable of the piped process containing the password.
+	PasswordEnv string `json:"password_env,omitempty"`
+	// The schema managed by psqldef. Empty means "public".
+	// Only used for PostgreSQL.
+	Schema string `json:"schema,omitempty"`
+	// The TLS mode used to connect.
+	// For MySQL, one of "disabled", "preferred" and "required".
+	// "required" encrypts the connection but does not verify the server certificate unless ssl_ca is set.
+	// For PostgreSQL, one of "disable", "allow", "prefer", "require", "verify-ca" and "verify-full".
+	SSLMode string `json:"sslmode,omitempty"`
+	// The path to the CA certificate file to verify the server certificate.
+	// Only used for MySQL and PostgreSQL.
+	SSLCA string `json:"ssl_ca,omitempty"`
+	// The paths to the client certificate and key files.
+	// Only used for PostgreSQL.
+	SSLCert string `json:"ssl_cert,omitempty"`
+	SSLKey  string `json:"ssl_key,omitempty"`
+	// The named instance of SQL Server, e.g. "SQLEXPRESS". The port is still required.
+	// Only used by SQLDEF_MIGRATE since mssqldef does not support it.
+	Instance string `json:"instance,omitempty"`
+	// The encryption of the connection, one of "disable", "false", "true" and "strict".
+	// Only used by SQLDEF_MIGRATE since mssqldef does not support it.
+	Encrypt string `json:"encrypt,omitempty"`
+	// Skip verifying the server certificate when the connection is encrypted.
+	// Only used by SQLDEF_MIGRATE since mssqldef does not support it.
+	TrustServerCertificate bool `json:"trust_server_certificate,omitempty"`
+	// The path to the Unix socket to connect instead of host and port.
+	// For PostgreSQL, it is the directory containing the socket.
+	// Only used for MySQL and PostgreSQL.
+	Socket string `json:"socket,omitempty"`
+	// The path to the database file.
+	// Only used for SQLite, which does not use the connection info above.
+	Path string `json:"path,omitempty"`
 }
 
 type ApplicationConfigSpec struct {
+	// The schema files to deploy, relative to the application directory.
+	// Each entry can be a file path or a glob pattern. The matched files are concatenated
+	// in the order of the entries to build the desired schema. Files matched by a glob pattern
+	// are sorted by their paths.
+	// When empty, the only .sql file in the application directory is used.
+	SchemaFiles []string `json:"schemaFiles,omitempty"`
+	// How to handle destructive changes such as DROP TABLE, DROP COLUMN and column type changes.
+	// One of "allow", "deny" and "approval". Default is "allow".
+	DestructiveChanges DestructiveChangePolicy `json:"destructiveChanges,omitempty"`
+	// The versioned SQL scripts run by SQLDEF_MIGRATE stages, e.g. data backfills.
+	Migrations MigrationsConfig `json:"migrations,omitempty"`
+	// Run ALTER TABLE on large MySQL tables with gh-ost instead of sqldef to avoid locking them.
+	OnlineSchemaChange OnlineSchemaChangeConfig `json:"onlineSchemaChange,omitempty"`
+	// The rules checked against the schema files by SQLDEF_LINT stages.
+	Lint LintConfig `json:"lint,omitempty"`
+	// How the stages run against multiple deploy targets.
+	Execution ExecutionConfig `json:"execution,omitempty"`
+	// The regular expressions of the tables managed by sqldef. All tables are managed when empty.
+	// Each pattern must match the whole table name, which psqldef qualifies with the schema, e.g. `public\.users`.
+	TargetTables []string `json:"targetTables,omitempty"`
+	// The regular expressions of the tables not managed by sqldef, e.g. the tables owned by other tools.
+	// sqldef neither creates, alters nor drops them, and they are excluded from the exported schemas.
+	SkipTables []string `json:"skipTables,omitempty"`
+	// Do not manage views.
+	SkipViews bool `json:"skipViews,omitempty"`
+}
+
+// DefaultMigrationsTable is the default name of the table recording the applied migration scripts.
+const DefaultMigrationsTable = "sqldef_migrations"
+
+var migrationsTableRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// MigrationsConfig is the config of the versioned SQL scripts.
+// Each script runs in a transaction with its record in the tracking table. MySQL commits DDL implicitly,
+// so a MySQL script failing after DDL leaves the DDL applied without the record. Keep DDL in the schema files,
+// or make such scripts safe to run again.
+type MigrationsConfig struct {
+	// The directory containing the scripts, relative to the application directory.
+	// Each script is named "<version>_<description>.sql" where the version is a positive integer,
+	// e.g. "20250101120000_backfill_users.sql". The scripts are run in the order of their versions.
+	Dir string `json:"dir,omitempty"`
+	// The table in the target database recording the applied scripts.
+	// The table is excluded from the schema managed by sqldef. Default is "sqldef_migrations".
+	Table string `json:"table,omitempty"`
+}
+
+// Enabled returns true when the migration scripts are configured.
+func (c MigrationsConfig) Enabled() bool {
+	return c.Dir != ""
+}
+
+// TableOrDefault returns the configured name of the table recording the applied scripts or the default one.
+func (c MigrationsConfig) TableOrDefault() string {
+	return cmp.Or(c.Table, DefaultMigrationsTable)
+}
+
+// OnlineSchemaChangeConfig is the config to run ALTER TABLE with gh-ost.
+// It only applies to MySQL deploy targets.
+type OnlineSchemaChangeConfig struct {
+	// The tables whose ALTER TABLE statements are run with gh-ost.
+	Tables []string `json:"tables,omitempty"`
+	// ALTER TABLE statements on the tables larger than this size in MiB are also run with gh-ost.
+	// Zero disables the threshold.
+	MinTableSizeMB int64 `json:"minTableSizeMB,omitempty"`
+	// Additional flags passed to gh-ost, e.g. "--allow-on-master" or "--max-load=Threads_running=25".
+	// gh-ost connects to the host of the deploy target as a replica by default,
+	// so "--allow-on-master" is needed when the host is the primary.
+	Args []string `json:"args,omitempty"`
+}
+
+// Enabled returns true when any table can be altered with gh-ost.
+func (c OnlineSchemaChangeConfig) Enabled() bool {
+	return len(c.Tables) > 0 || c.MinTableSizeMB > 0
+}
+
+// validateTablePatterns checks the regular expressions of the tables passed to sqldef.
+// sqldef reads them separated by newlines, so they must be single lines.
+func validateTablePatterns(field string, patterns []string) error {
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("%s must not contain an empty entry", field)
+		}
+		if strings.ContainsAny(p, "\r\n") {
+			return fmt.Errorf("%s must not contain a line break: %q", field, p)
+		}
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid %s pattern %q: %w", field, p, err)
+		}
+	}
+	return nil
+}
+
+// OnErrorPolicy is the policy for a failure on a deploy target when the stage runs against multiple deploy targets.
+type OnErrorPolicy string
+
+const (
+	// OnErrorFailFast does not start the remaining deploy targets after a failure.
+	// The deploy targets already started are run to the end.
+	OnErrorFailFast OnErrorPolicy = "failFast"
+	// OnErrorContinue runs all deploy targets regardless of failures. The stage fails if any of them fails.
+	OnErrorContinue OnErrorPolicy = "continue"
+)
+
+// ExecutionConfig controls how the stages run against multiple deploy targets.
+type ExecutionConfig struct {
+	// The maximum number of deploy targets processed at the same time. Default is 1.
+	Concurrency int `json:"concurrency,omitempty"`
+	// How to handle a failure on a deploy target. One of "failFast" and "continue". Default is "failFast".
+	OnError OnErrorPolicy `json:"onError,omitempty"`
+}
+
+// ConcurrencyOrDefault returns the maximum number of deploy targets processed at the same time.
+func (c ExecutionConfig) ConcurrencyOrDefault() int {
+	return max(c.Concurrency, 1)
+}
+
+// ContinueOnError returns true when the remaining deploy targets are run after a failure.
+func (c ExecutionConfig) ContinueOnError() bool {
+	return c.OnError == OnErrorContinue
+}
+
+// LintSeverity is the severity of the violations of a lint rule.
+type LintSeverity string
+
+const (
+	// LintSeverityOff disables the rule. It is the default.
+	LintSeverityOff LintSeverity = "off"
+	// LintSeverityWarning reports the violations without failing the stage.
+	LintSeverityWarning LintSeverity = "warning"
+	// LintSeverityError reports the violations and fails the stage.
+	LintSeverityError LintSeverity = "error"
+)
+
+// Enabled returns true when the rule is checked.
+func (s LintSeverity) Enabled() bool {
+	return s == LintSeverityWarning || s == LintSeverityError
+}
+
+// DefaultMoneyColumnPattern is the default pattern of the names of the columns storing money.
+const DefaultMoneyColumnPattern = `(?i)(price|amount|cost|fee|balance)`
+
+// DefaultLintCharset is the default charset required by the charset lint rule.
+const DefaultLintCharset = "utf8mb4"
+
+// LintConfig is the rules checked by SQLDEF_LINT stages.
+// Each rule is disabled unless its severity is set to "warning" or "error".
+type LintConfig struct {
+	// Every table has a primary key.
+	PrimaryKey LintRule `json:"primaryKey,omitempty"`
+	// The table names match the pattern.
+	TableName LintPatternRule `json:"tableName,omitempty"`
+	// The column names match the pattern.
+	ColumnName LintPatternRule `json:"columnName,omitempty"`
+	// The columns whose names match the pattern are not FLOAT, DOUBLE or REAL.
+	// The pattern defaults to DefaultMoneyColumnPattern.
+	MoneyColumn LintPatternRule `json:"moneyColumn,omitempty"`
+	// The tables and columns use the charset. This rule is for MySQL.
+	Charset LintCharsetRule `json:"charset,omitempty"`
+	// Every foreign key has an index whose leading columns are the columns of the foreign key.
+	ForeignKeyIndex LintRule `json:"foreignKeyIndex,omitempty"`
+}
+
+// Enabled returns true when any rule is checked.
+func (c LintConfig) Enabled() bool {
+	return c.PrimaryKey.Severity.Enabled() ||
+		c.TableName.Severity.Enabled() ||
+		c.ColumnName.Severity.Enabled() ||
+		c.MoneyColumn.Severity.Enabled() ||
+		c.Charset.Severity.Enabled() ||
+		c.ForeignKeyIndex.Severity.Enabled()
+}
+
+// LintRule is a lint rule without parameters.
+type LintRule struct {
+	// One of "off", "warning" and "error". Default is "off".
+	Severity LintSeverity `json:"severity,omitempty"`
+}
+
+// LintPatternRule is a lint rule checking names with a regular expression.
+type LintPatternRule struct {
+	// One of "off", "warning" and "error". Default is "off".
+	Severity LintSeverity `json:"severity,omitempty"`
+	// The regular expression matched against the names.
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// LintCharsetRule is a lint rule checking the charset.
+type LintCharsetRule struct {
+	// One of "off", "warning" and "error". Default is "off".
+	Severity LintSeverity `json:"severity,omitempty"`
+	// The required charset. Default is DefaultLintCharset.
+	Charset string `json:"charset,omitempty"`
+}
+
+// CharsetOrDefault returns the required charset.
+func (r LintCharsetRule) CharsetOrDefault() string {
+	return cmp.Or(r.Charset, DefaultLintCharset)
+}
+
+// Validate checks the severities and the patterns of the rules.
+func (c LintConfig) Validate() error {
+	severities := map[string]LintSeverity{
+		"primaryKey":      c.PrimaryKey.Severity,
+		"tableName":       c.TableName.Severity,
+		"columnName":      c.ColumnName.Severity,
+		"moneyColumn":     c.MoneyColumn.Severity,
+		"charset":         c.Charset.Severity,
+		"foreignKeyIndex": c.ForeignKeyIndex.Severity,
+	}
+	for name, s := range severities {
+		switch s {
+		case "", LintSeverityOff, LintSeverityWarning, LintSeverityError:
+		default:
+			return fmt.Errorf("invalid lint.%s.severity %q, must be one of off, warning and error", name, s)
+		}
+	}
+	patterns := map[string]LintPatternRule{
+		"tableName":   c.TableName,
+		"columnName":  c.ColumnName,
+		"moneyColumn": c.MoneyColumn,
+	}
+	for name, r := range patterns {
+		if r.Pattern == "" {
+			if r.Severity.Enabled() && name != "moneyColumn" {
+				return fmt.Errorf("lint.%s.pattern is required", name)
+			}
+			continue
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid lint.%s.pattern %q: %w", name, r.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// SqldefExportStageOptions contains all configurable values for a SQLDEF_EXPORT stage.
+type SqldefExportStageOptions struct {
+	// The name of the deploy target to export the schema of.
+	// Required when the application has multiple deploy targets.
+	Target string `json:"target"`
+}
+
+// SqldefMigrateStageOptions contains all configurable values for a SQLDEF_MIGRATE stage.
+type SqldefMigrateStageOptions struct {
+	// List the pending scripts without running them.
+	DryRun bool `json:"dryRun"`
+}
+
+// SqldefPlanStageOptions contains all configurable values for a SQLDEF_PLAN stage.
+type SqldefPlanStageOptions struct {
+	// Exit the pipeline if the result is "No Changes" with success status.
+	ExitOnNoChanges bool `json:"exitOnNoChanges"`
+}
+
+var sslModes = map[DBType][]string{
+	DBTypeMySQL:    {"disabled", "preferred", "required"},
+	DBTypePostgres: {"disable", "allow", "prefer", "require", "verify-ca", "verify-full"},
+}
+
+// mssqlEncryptModes are the values of the encrypt option of SQL Server.
+var mssqlEncryptModes = []string{"disable", "false", "true", "strict"}
+
+// Validate checks the deploy target config before running any sqldef command.
+func (c DeployTargetConfig) Validate() error {
+	switch c.DbType {
+	case DBTypeMySQL, DBTypePostgres, DBTypeMSSQL:
+		if c.DBName == "" {
+			return errors.New("db_name is required")
+		}
+	case DBTypeSQLite:
+		if c.Path == "" {
+			return errors.New("path is required for sqlite")
+		}
+	default:
+		return fmt.Errorf("unsupported database type: %s", c.DbType)
+	}
+
+	if c.SSLMode != "" {
+		modes, ok := sslModes[c.DbType]
+		if !ok {
+			return fmt.Errorf("sslmode is not supported for %s", c.DbType)
+		}
+		if !slices.Contains(modes, strings.ToLower(c.SSLMode)) {
+			return fmt.Errorf("invalid sslmode %q for %s, must be one of %s", c.SSLMode, c.DbType, strings.Join(modes, ", "))
+		}
+	}
+
+	if c.SSLCA != "" && c.DbType != DBTypeMySQL && c.DbType != DBTypePostgres {
+		return fmt.Errorf("ssl_ca is not supported for %s", c.DbType)
+	}
+	if (c.SSLCert != "" || c.SSLKey != "") && c.DbType != DBTypePostgres {
+		return fmt.Errorf("ssl_cert and ssl_key are not supported for %s", c.DbType)
+	}
+	if (c.SSLCert == "") != (c.SSLKey == "") {
+		return errors.New("ssl_cert and ssl_key must be set together")
+	}
+	for name, path := range map[string]string{"ssl_ca": c.SSLCA, "ssl_cert": c.SSLCert, "ssl_key": c.SSLKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	if c.DbType != DBTypeMSSQL && (c.Instance != "" || c.Encrypt != "" || c.TrustServerCertificate) {
+		return fmt.Errorf("instance, encrypt and trust_server_certificate are not supported for %s", c.DbType)
+	}
+	if c.Instance != "" && c.Port == "" {
+		return errors.New("port is required with instance because mssqldef does not resolve instance names")
+	}
+	if c.Encrypt != "" && !slices.Contains(mssqlEncryptModes, strings.ToLower(c.Encrypt)) {
+		return fmt.Errorf("invalid encrypt %q, must be one of %s", c.Encrypt, strings.Join(mssqlEncryptModes, ", "))
+	}
+
+	if c.Socket != "" {
+		if c.DbType != DBTypeMySQL && c.DbType != DBTypePostgres {
+			return fmt.Errorf("socket is not supported for %s", c.DbType)
+		}
+		if c.Host != "" || c.Port != "" {
+			return errors.New("socket cannot be used with host or port")
+		}
+	}
+	return nil
+}
+
+// LoadPassword returns the password from one of password, password_file and password_env.
+// It returns an empty string when none of them is set.
+func (c DeployTargetConfig) LoadPassword() (string, error) {
+	var set int
+	for _, v := range []string{c.Password, c.PasswordFile, c.PasswordEnv} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", errors.New("only one of password, password_file and password_env can be set")
+	}
+
+	switch {
+	case c.PasswordFile != "":
+		data, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password_file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case c.PasswordEnv != "":
+		v, ok := os.LookupEnv(c.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s given by password_env is not set", c.PasswordEnv)
+		}
+		return v, nil
+	default:
+		return c.Password, nil
+	}
+}
+
+// DestructiveChangesOrDefault returns the configured policy for destructive changes or the default one.
+func (s ApplicationConfigSpec) DestructiveChangesOrDefault() DestructiveChangePolicy {
+	return cmp.Or(s.DestructiveChanges, DestructiveChangePolicyAllow)
+}
+
+func (s ApplicationConfigSpec) Validate() error {
+	for _, f := range s.SchemaFiles {
+		if f == "" {
+			return errors.New("schemaFiles must not contain an empty entry")
+		}
+		// The files must not be read from outside the application directory.
+		if !filepath.IsLocal(f) {
+			return fmt.Errorf("schemaFiles must be within the application directory: %s", f)
+		}
+		if _, err := filepath.Match(f, ""); err != nil {
+			return fmt.Errorf("invalid schemaFiles pattern %q: %w", f, err)
+		}
+	}
+	switch s.DestructiveChanges {
+	case "", DestructiveChangePolicyAllow, DestructiveChangePolicyDeny, DestructiveChangePolicyApproval:
+	default:
+		return fmt.Errorf("invalid destructiveChanges %q, must be one of allow, deny and approval", s.DestructiveChanges)
+	}
+	if s.Migrations.Dir != "" && !filepath.IsLocal(s.Migrations.Dir) {
+		return fmt.Errorf("migrations.dir must be within the application directory: %s", s.Migrations.Dir)
+	}
+	if s.Migrations.Table != "" && !migrationsTableRegex.MatchString(s.Migrations.Table) {
+		return fmt.Errorf("invalid migrations.table %q, must consist of lowercase letters, digits and underscores", s.Migrations.Table)
+	}
+	if s.OnlineSchemaChange.MinTableSizeMB < 0 {
+		return fmt.Errorf("onlineSchemaChange.minTableSizeMB must not be negative: %d", s.OnlineSchemaChange.MinTableSizeMB)
+	}
+	for _, t := range s.OnlineSchemaChange.Tables {
+		if t == "" {
+			return errors.New("onlineSchemaChange.tables must not contain an empty entry")
+		}
+	}
+	for _, a := range s.OnlineSchemaChange.Args {
+		if !strings.HasPrefix(a, "-") {
+			return fmt.Errorf("onlineSchemaChange.args must be flags: %s", a)
+		}
+	}
+	if err := validateTablePatterns("targetTables", s.TargetTables); err != nil {
+		return err
+	}
+	if err := validateTablePatterns("skipTables", s.SkipTables); err != nil {
+		return err
+	}
+	if s.Execution.Concurrency < 0 {
+		return fmt.Errorf("execution.concurrency must not be negative: %d", s.Execution.Concurrency)
+	}
+	switch s.Execution.OnError {
+	case "", OnErrorFailFast, OnErrorContinue:
+	default:
+		return fmt.Errorf("invalid execution.onError %q, must be one of failFast and continue", s.Execution.OnError)
+	}
+	return s.Lint.Validate()
 }
@@ -0,0 +1,356 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationConfigSpec_Validate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		spec    ApplicationConfigSpec
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			spec: ApplicationConfigSpec{},
+		},
+		{
+			name: "valid files and patterns",
+			spec: ApplicationConfigSpec{SchemaFiles: []string{"schema/tables.sql", "schema/views/*.sql"}},
+		},
+		{
+			name: "valid destructiveChanges",
+			spec: ApplicationConfigSpec{DestructiveChanges: DestructiveChangePolicyApproval},
+		},
+		{
+			name:    "invalid destructiveChanges",
+			spec:    ApplicationConfigSpec{DestructiveChanges: "ask"},
+			wantErr: true,
+		},
+		{
+			name:    "empty entry",
+			spec:    ApplicationConfigSpec{SchemaFiles: []string{""}},
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			spec:    ApplicationConfigSpec{SchemaFiles: []string{"/etc/schema.sql"}},
+			wantErr: true,
+		},
+		{
+			name:    "schema file outside the application directory",
+			spec:    ApplicationConfigSpec{SchemaFiles: []string{"schema/../../shared/schema.sql"}},
+			wantErr: true,
+		},
+		{
+			name:    "malformed pattern",
+			spec:    ApplicationConfigSpec{SchemaFiles: []string{"schema/[.sql"}},
+			wantErr: true,
+		},
+		{
+			name: "valid migrations",
+			spec: ApplicationConfigSpec{Migrations: MigrationsConfig{Dir: "migrations", Table: "schema_versions"}},
+		},
+		{
+			name:    "absolute migrations dir",
+			spec:    ApplicationConfigSpec{Migrations: MigrationsConfig{Dir: "/migrations"}},
+			wantErr: true,
+		},
+		{
+			name:    "migrations dir outside the application directory",
+			spec:    ApplicationConfigSpec{Migrations: MigrationsConfig{Dir: "../migrations"}},
+			wantErr: true,
+		},
+		{
+			name: "valid onlineSchemaChange",
+			spec: ApplicationConfigSpec{OnlineSchemaChange: OnlineSchemaChangeConfig{Tables: []string{"events"}, MinTableSizeMB: 1024, Args: []string{"--allow-on-master"}}},
+		},
+		{
+			name:    "negative onlineSchemaChange size",
+			spec:    ApplicationConfigSpec{OnlineSchemaChange: OnlineSchemaChangeConfig{MinTableSizeMB: -1}},
+			wantErr: true,
+		},
+		{
+			name:    "onlineSchemaChange args without flag",
+			spec:    ApplicationConfigSpec{OnlineSchemaChange: OnlineSchemaChangeConfig{Tables: []string{"events"}, Args: []string{"execute"}}},
+			wantErr: true,
+		},
+		{
+			name: "valid lint",
+			spec: ApplicationConfigSpec{Lint: LintConfig{
+				PrimaryKey:  LintRule{Severity: LintSeverityError},
+				TableName:   LintPatternRule{Severity: LintSeverityWarning, Pattern: "^[a-z][a-z0-9_]*$"},
+				MoneyColumn: LintPatternRule{Severity: LintSeverityError},
+			}},
+		},
+		{
+			name:    "invalid lint severity",
+			spec:    ApplicationConfigSpec{Lint: LintConfig{PrimaryKey: LintRule{Severity: "fatal"}}},
+			wantErr: true,
+		},
+		{
+			name:    "lint naming rule without pattern",
+			spec:    ApplicationConfigSpec{Lint: LintConfig{ColumnName: LintPatternRule{Severity: LintSeverityError}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid lint pattern",
+			spec:    ApplicationConfigSpec{Lint: LintConfig{TableName: LintPatternRule{Severity: LintSeverityError, Pattern: "["}}},
+			wantErr: true,
+		},
+		{
+			name: "valid execution",
+			spec: ApplicationConfigSpec{Execution: ExecutionConfig{Concurrency: 4, OnError: OnErrorContinue}},
+		},
+		{
+			name:    "negative execution concurrency",
+			spec:    ApplicationConfigSpec{Execution: ExecutionConfig{Concurrency: -1}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid execution onError",
+			spec:    ApplicationConfigSpec{Execution: ExecutionConfig{OnError: "ignore"}},
+			wantErr: true,
+		},
+		{
+			name: "valid managed objects",
+			spec: ApplicationConfigSpec{TargetTables: []string{`public\.users`}, SkipTables: []string{"schema_migrations", "good_job.*"}, SkipViews: true},
+		},
+		{
+			name:    "invalid skipTables pattern",
+			spec:    ApplicationConfigSpec{SkipTables: []string{"jobs("}},
+			wantErr: true,
+		},
+		{
+			name:    "targetTables with line break",
+			spec:    ApplicationConfigSpec{TargetTables: []string{"users\nposts"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid migrations table",
+			spec:    ApplicationConfigSpec{Migrations: MigrationsConfig{Dir: "migrations", Table: "versions; DROP TABLE users"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.spec.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestApplicationConfigSpec_DestructiveChangesOrDefault(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DestructiveChangePolicyAllow, ApplicationConfigSpec{}.DestructiveChangesOrDefault())
+	assert.Equal(t, DestructiveChangePolicyDeny, ApplicationConfigSpec{DestructiveChanges: DestructiveChangePolicyDeny}.DestructiveChangesOrDefault())
+}
+
+func TestDeployTargetConfig_LoadPassword(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0600))
+	t.Setenv("SQLDEF_TEST_PASSWORD", "from-env")
+
+	testcases := []struct {
+		name     string
+		cfg      DeployTargetConfig
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "no password",
+			cfg:  DeployTargetConfig{},
+		},
+		{
+			name:     "password",
+			cfg:      DeployTargetConfig{Password: "plain"},
+			expected: "plain",
+		},
+		{
+			name:     "password_file",
+			cfg:      DeployTargetConfig{PasswordFile: passwordFile},
+			expected: "from-file",
+		},
+		{
+			name:     "password_env",
+			cfg:      DeployTargetConfig{PasswordEnv: "SQLDEF_TEST_PASSWORD"},
+			expected: "from-env",
+		},
+		{
+			name:    "missing password_file",
+			cfg:     DeployTargetConfig{PasswordFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "unset password_env",
+			cfg:     DeployTargetConfig{PasswordEnv: "SQLDEF_TEST_UNSET_PASSWORD"},
+			wantErr: true,
+		},
+		{
+			name:    "multiple sources",
+			cfg:     DeployTargetConfig{Password: "plain", PasswordEnv: "SQLDEF_TEST_PASSWORD"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.cfg.LoadPassword()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestDeployTargetConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pem")
+	cert := filepath.Join(dir, "client.pem")
+	key := filepath.Join(dir, "client.key")
+	for _, f := range []string{ca, cert, key} {
+		require.NoError(t, os.WriteFile(f, []byte("dummy"), 0600))
+	}
+
+	testcases := []struct {
+		name    string
+		cfg     DeployTargetConfig
+		wantErr bool
+	}{
+		{
+			name: "mysql with tls",
+			cfg:  DeployTargetConfig{DbType: DBTypeMySQL, DBName: "db", Host: "db.example.com", SSLMode: "REQUIRED", SSLCA: ca},
+		},
+		{
+			name: "mysql with socket",
+			cfg:  DeployTargetConfig{DbType: DBTypeMySQL, DBName: "db", Socket: "/var/run/mysqld/mysqld.sock"},
+		},
+		{
+			name: "postgres with client certificate",
+			cfg:  DeployTargetConfig{DbType: DBTypePostgres, DBName: "db", SSLMode: "verify-full", SSLCA: ca, SSLCert: cert, SSLKey: key},
+		},
+		{
+			name: "mssql with tls",
+			cfg:  DeployTargetConfig{DbType: DBTypeMSSQL, DBName: "db", Host: "db.example.com", Port: "1434", Instance: "SQLEXPRESS", Encrypt: "strict", TrustServerCertificate: true},
+		},
+		{
+			name:    "mssql instance without port",
+			cfg:     DeployTargetConfig{DbType: DBTypeMSSQL, DBName: "db", Host: "db.example.com", Instance: "SQLEXPRESS"},
+			wantErr: true,
+		},
+		{
+			name: "sqlite",
+			cfg:  DeployTargetConfig{DbType: DBTypeSQLite, Path: "test.db"},
+		},
+		{
+			name:    "unsupported db type",
+			cfg:     DeployTargetConfig{DbType: "oracle", DBName: "db"},
+			wantErr: true,
+		},
+		{
+			name:    "missing db_name",
+			cfg:     DeployTargetConfig{DbType: DBTypeMySQL},
+			wantErr: true,
+		},
+		{
+			name:    "missing sqlite path",
+			cfg:     DeployTargetConfig{DbType: DBTypeSQLite},
+			wantErr: true,
+		},
+		{
+			name:    "postgres sslmode for mysql",
+			cfg:     DeployTargetConfig{DbType: DBTypeMySQL, DBName: "db", SSLMode: "verify-full"},
+			wantErr: true,
+		},
+		{
+			name:    "sslmode for mssql",
+			cfg:     DeployTargetConfig{DbType: DBTypeMSSQL, DBName: "db", SSLMode: "require"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid encrypt for mssql",
+			cfg:     DeployTargetConfig{DbType: DBTypeMSSQL, DBName: "db", Encrypt: "required"},
+			wantErr: true,
+		},
+		{
+			name:    "encrypt for postgres",
+			cfg:     DeployTargetConfig{DbType: DBTypePostgres, DBName: "db", Encrypt: "true"},
+			wantErr: true,
+		},
+		{
+			name:    "instance for mysql",
+			cfg:     DeployTargetConfig{DbType: DBTypeMySQL, DBName: "db", Instance: "SQLEXPRESS"},
+			wantErr: true,
+		},
+		{
+			name:    "client certificate for mysql",
+			cfg:     DeployTargetConfig{DbType: DBTypeMySQL, DBName: "db", SSLCert: cert, SSLKey: key},
+			wantErr: true,
+		},
+		{
+			name:    "client certificate without key",
+			cfg:     DeployTargetConfig{DbType: DBTypePostgres, DBName: "db", SSLCert: cert},
+			wantErr: true,
+		},
+		{
+			name:    "missing ca file",
+			cfg:     DeployTargetConfig{DbType: DBTypePostgres, DBName: "db", SSLCA: filepath.Join(dir, "missing.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "socket with host",
+			cfg:     DeployTargetConfig{DbType: DBTypeMySQL, DBName: "db", Host: "localhost", Socket: "/tmp/mysql.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "socket for mssql",
+			cfg:     DeployTargetConfig{DbType: DBTypeMSSQL, DBName: "db", Socket: "/tmp/mssql.sock"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.cfg.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
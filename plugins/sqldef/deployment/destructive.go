@@ -0,0 +1,154 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// destructiveChange is a statement found in the dry run which may lose data.
+type destructiveChange struct {
+	Kind      string
+	Statement string
+}
+
+var (
+	dropTableRegex  = regexp.MustCompile(`(?i)^DROP\s+TABLE\b`)
+	dropSchemaRegex = regexp.MustCompile(`(?i)^DROP\s+(SCHEMA|DATABASE)\b`)
+	truncateRegex   = regexp.MustCompile(`(?i)^TRUNCATE\b`)
+	alterTableRegex = regexp.MustCompile(`(?i)^ALTER\s+TABLE\b`)
+	dropColumnRegex = regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`)
+	// MySQL changes the column definition including its type with MODIFY or CHANGE.
+	modifyColumnRegex = regexp.MustCompile(`(?i)\b(MODIFY|CHANGE)\s+(COLUMN\s+)?\S`)
+	// The first word after the column name tells what ALTER COLUMN changes,
+	// e.g. "TYPE" for PostgreSQL, "SET DEFAULT" for the default value or the new type for SQL Server.
+	alterColumnRegex = regexp.MustCompile("(?i)\\bALTER\\s+COLUMN\\s+(?:\"[^\"]+\"|`[^`]+`|\\[[^\\]]+\\]|\\S+)\\s+(\\w+)(?:\\s+(\\w+))?")
+)
+
+// alterColumnKeywords are the first words after the column name of ALTER COLUMN which do not change the column type.
+var alterColumnKeywords = map[string]struct{}{
+	"SET":     {},
+	"DROP":    {},
+	"ADD":     {},
+	"RESET":   {},
+	"RESTART": {},
+	"OPTIONS": {},
+}
+
+// classifyStatement returns the kind of the destructive change made by the given statement,
+// or an empty string if the statement is not destructive.
+func classifyStatement(stmt string) string {
+	switch {
+	case dropTableRegex.MatchString(stmt):
+		return "DROP TABLE"
+	case dropSchemaRegex.MatchString(stmt):
+		return "DROP SCHEMA"
+	case truncateRegex.MatchString(stmt):
+		return "TRUNCATE"
+	case !alterTableRegex.MatchString(stmt):
+		return ""
+	case dropColumnRegex.MatchString(stmt):
+		return "DROP COLUMN"
+	case modifyColumnRegex.MatchString(stmt):
+		return "COLUMN TYPE CHANGE"
+	}
+
+	if m := alterColumnRegex.FindStringSubmatch(stmt); m != nil {
+		keyword := strings.ToUpper(m[1])
+		if keyword == "TYPE" || (keyword == "SET" && strings.EqualFold(m[2], "DATA")) {
+			return "COLUMN TYPE CHANGE"
+		}
+		if _, ok := alterColumnKeywords[keyword]; !ok {
+			return "COLUMN TYPE CHANGE"
+		}
+	}
+	return ""
+}
+
+// classifyDestructiveChanges returns the destructive changes found in the statements of the sqldef dry run.
+func classifyDestructiveChanges(stmts []string) []destructiveChange {
+	var changes []destructiveChange
+	for _, stmt := range stmts {
+		if kind := classifyStatement(stmt); kind != "" {
+			changes = append(changes, destructiveChange{Kind: kind, Statement: stmt})
+		}
+	}
+	return changes
+}
+
+// reportDestructiveChanges logs the given destructive changes of the deploy target.
+func reportDestructiveChanges(lp sdk.StageLogPersister, target string, changes []destructiveChange) {
+	if len(changes) == 0 {
+		lp.Infof("Deploy Target [%s]: no destructive changes found", target)
+		return
+	}
+	lp.Infof("Deploy Target [%s]: found %d destructive changes", target, len(changes))
+	for _, c := range changes {
+		lp.Infof("  [%s] %s", c.Kind, c.Statement)
+	}
+}
+
+// destructivePolicy returns the policy for destructive changes of the given application config spec.
+func destructivePolicy(spec *config.ApplicationConfigSpec) config.DestructiveChangePolicy {
+	if spec == nil {
+		return config.ApplicationConfigSpec{}.DestructiveChangesOrDefault()
+	}
+	return spec.DestructiveChangesOrDefault()
+}
+
+// enforceDestructivePolicy checks whether the destructive changes can be applied under the given policy.
+// It waits for a manual approval of the stage when the policy requires it.
+func enforceDestructivePolicy(ctx context.Context, client *sdk.Client, lp sdk.StageLogPersister, policy config.DestructiveChangePolicy, found int) error {
+	if found == 0 {
+		return nil
+	}
+
+	switch policy {
+	case config.DestructiveChangePolicyAllow:
+		lp.Infof("Applying %d destructive changes since destructiveChanges is %q", found, policy)
+		return nil
+	case config.DestructiveChangePolicyApproval:
+		lp.Infof("Waiting for an approval to apply %d destructive changes", found)
+		return waitForApproval(ctx, client, lp)
+	default:
+		return fmt.Errorf("found %d destructive changes, set destructiveChanges to %q or %q to apply them", found, config.DestructiveChangePolicyAllow, config.DestructiveChangePolicyApproval)
+	}
+}
+
+// waitForApproval blocks until the stage is approved or the context is cancelled.
+func waitForApproval(ctx context.Context, client *sdk.Client, lp sdk.StageLogPersister) error {
+	for cmd, err := range client.ListStageCommands(ctx, sdk.CommandTypeApproveStage) {
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lp.Errorf("Failed to list stage commands (%v)", err)
+			continue
+		}
+		lp.Infof("Got an approval from %s", cmd.Commander)
+		if err := client.PutStageMetadata(ctx, sdk.MetadataKeyStageApprovedUsers, cmd.Commander); err != nil {
+			lp.Errorf("Failed to save the approver (%v)", err)
+		}
+		return nil
+	}
+	return ctx.Err()
+}
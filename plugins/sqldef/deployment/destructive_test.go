@@ -0,0 +1,249 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+)
+
+func TestClassifyStatement(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		stmt     string
+		expected string
+	}{
+		{stmt: "DROP TABLE `users`;", expected: "DROP TABLE"},
+		{stmt: `drop table "public"."users";`, expected: "DROP TABLE"},
+		{stmt: "DROP SCHEMA app;", expected: "DROP SCHEMA"},
+		{stmt: "TRUNCATE users;", expected: "TRUNCATE"},
+		{stmt: "ALTER TABLE `users` DROP COLUMN `email`;", expected: "DROP COLUMN"},
+		{stmt: "ALTER TABLE `users` MODIFY COLUMN `name` varchar(10) NOT NULL;", expected: "COLUMN TYPE CHANGE"},
+		{stmt: "ALTER TABLE `users` CHANGE COLUMN `name` `name` varchar(10);", expected: "COLUMN TYPE CHANGE"},
+		{stmt: `ALTER TABLE "public"."users" ALTER COLUMN "name" TYPE varchar(10);`, expected: "COLUMN TYPE CHANGE"},
+		{stmt: `ALTER TABLE users ALTER COLUMN name SET DATA TYPE text;`, expected: "COLUMN TYPE CHANGE"},
+		{stmt: `ALTER TABLE [dbo].[users] ALTER COLUMN [name] nvarchar(10);`, expected: "COLUMN TYPE CHANGE"},
+		{stmt: `ALTER TABLE "public"."users" ALTER COLUMN "name" SET DEFAULT 'x';`, expected: ""},
+		{stmt: `ALTER TABLE users ALTER COLUMN name DROP NOT NULL;`, expected: ""},
+		{stmt: "ALTER TABLE `users` ADD COLUMN `email` varchar(255);", expected: ""},
+		{stmt: "ALTER TABLE `users` DROP INDEX `idx_name`;", expected: ""},
+		{stmt: "DROP INDEX idx_name;", expected: ""},
+		{stmt: "CREATE TABLE users (id INT);", expected: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.stmt, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, classifyStatement(tc.stmt))
+		})
+	}
+}
+
+func TestEnforceDestructivePolicy(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		policy  config.DestructiveChangePolicy
+		found   int
+		wantErr bool
+	}{
+		{name: "no destructive changes", policy: config.DestructiveChangePolicyDeny, found: 0},
+		{name: "allow", policy: config.DestructiveChangePolicyAllow, found: 2},
+		{name: "deny", policy: config.DestructiveChangePolicyDeny, found: 1, wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := enforceDestructivePolicy(t.Context(), nil, logpersistertest.NewTestLogPersister(t), tc.policy, tc.found)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func newDestructiveStageInput(t *testing.T, stageName string, policy config.DestructiveChangePolicy) *sdk.ExecuteStageInput[config.ApplicationConfigSpec] {
+	t.Helper()
+	ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+		ApplicationDirectory: filepath.Join("testdata", "app"),
+		CommitHash:           "0123456789",
+		ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+			Spec: &config.ApplicationConfigSpec{DestructiveChanges: policy},
+		},
+	}
+	return &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:               stageName,
+			StageConfig:             []byte(``),
+			RunningDeploymentSource: ds,
+			TargetDeploymentSource:  ds,
+			Deployment: sdk.Deployment{
+				PipedID:       "piped-id",
+				ApplicationID: "app-id",
+			},
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+	}
+}
+
+func TestPlugin_DestructiveChanges(t *testing.T) {
+	t.Parallel()
+
+	const dropOutput = "-- dry run --\nBEGIN;\nDROP TABLE `users`;\nCOMMIT;\n"
+
+	testcases := []struct {
+		name     string
+		stage    string
+		policy   config.DestructiveChangePolicy
+		applied  bool
+		expected sdk.StageStatus
+	}{
+		{
+			name:     "plan only reports when denied",
+			stage:    sqldefStagePlan,
+			policy:   config.DestructiveChangePolicyDeny,
+			expected: sdk.StageStatusSuccess,
+		},
+		{
+			name:     "plan succeeds when approval is required",
+			stage:    sqldefStagePlan,
+			policy:   config.DestructiveChangePolicyApproval,
+			expected: sdk.StageStatusSuccess,
+		},
+		{
+			name:     "apply fails without applying when denied",
+			stage:    sqldefStageApply,
+			policy:   config.DestructiveChangePolicyDeny,
+			expected: sdk.StageStatusFailure,
+		},
+		{
+			name:     "apply applies when allowed",
+			stage:    sqldefStageApply,
+			policy:   config.DestructiveChangePolicyAllow,
+			applied:  true,
+			expected: sdk.StageStatusSuccess,
+		},
+		{
+			name:     "apply applies by default",
+			stage:    sqldefStageApply,
+			applied:  true,
+			expected: sdk.StageStatusSuccess,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockSqldef := &MockSqldefProvider{}
+			mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, true).Return(dropOutput, nil).Once()
+			if tc.applied {
+				mockSqldef.On("ShowCurrentSchema", ctx, mock.Anything).Return("", nil).Once()
+				mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, false).Return("", nil).Once()
+			}
+
+			deployTargets := []*sdk.DeployTarget[config.DeployTargetConfig]{
+				{
+					Name: "test-mysql",
+					Config: config.DeployTargetConfig{
+						DbType: config.DBTypeMySQL,
+						DBName: "testdb",
+					},
+				},
+			}
+
+			plugin := createPluginWithMockSqldef(mockSqldef)
+			input := newDestructiveStageInput(t, tc.stage, tc.policy)
+
+			var status sdk.StageStatus
+			if tc.stage == sqldefStagePlan {
+				status = plugin.executePlanStage(ctx, deployTargets, input)
+			} else {
+				status = plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
+			}
+
+			assert.Equal(t, tc.expected, status)
+			mockSqldef.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPlugin_applyToTarget_ApprovedChanges(t *testing.T) {
+	t.Parallel()
+
+	const approvedOutput = "-- dry run --\nBEGIN;\nDROP TABLE `users`;\nCOMMIT;\n"
+
+	testcases := []struct {
+		name      string
+		dryRunOut string
+		wantErr   bool
+	}{
+		{
+			name:      "applies the approved changes",
+			dryRunOut: approvedOutput,
+		},
+		{
+			name:      "aborts when the changes differ from the approved ones",
+			dryRunOut: "-- dry run --\nBEGIN;\nDROP TABLE `users`;\nDROP TABLE `posts`;\nCOMMIT;\n",
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockSqldef := &MockSqldefProvider{}
+			mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, true).Return(tc.dryRunOut, nil).Once()
+			if !tc.wantErr {
+				mockSqldef.On("ShowCurrentSchema", ctx, mock.Anything).Return("", nil).Once()
+				mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, false).Return("", nil).Once()
+			}
+
+			target := &applyTarget{
+				name:       "test-mysql",
+				target:     provider.Target{Config: config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "testdb"}},
+				statements: provider.NewExecuteResult(approvedOutput).Statements,
+				approved:   true,
+			}
+			plugin := createPluginWithMockSqldef(mockSqldef)
+			err := plugin.applyToTarget(ctx, newFakeMetadataStore(), logpersistertest.NewTestLogPersister(t), nil, &config.ApplicationConfigSpec{}, target)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockSqldef.AssertExpectations(t)
+		})
+	}
+}
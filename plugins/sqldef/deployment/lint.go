@@ -0,0 +1,567 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// lintPos is a position in the schema files.
+type lintPos struct {
+	File string
+	Line int
+}
+
+// lintViolation is a violation of a lint rule found in the schema files.
+type lintViolation struct {
+	Pos      lintPos
+	Rule     string
+	Severity config.LintSeverity
+	Message  string
+}
+
+func (v lintViolation) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s]", v.Pos.File, v.Pos.Line, v.Message, v.Rule)
+}
+
+// sqlStatement is a statement in a schema file with the position where it starts.
+type sqlStatement struct {
+	Pos  lintPos
+	Text string
+}
+
+// posAt returns the position of the given offset in the statement.
+func (s sqlStatement) posAt(offset int) lintPos {
+	return lintPos{File: s.Pos.File, Line: s.Pos.Line + strings.Count(s.Text[:offset], "\n")}
+}
+
+// lintTable is a table defined in the schema files.
+type lintTable struct {
+	Name        string
+	Pos         lintPos
+	Columns     []lintColumn
+	PrimaryKey  bool
+	Indexes     [][]string
+	ForeignKeys []lintForeignKey
+	Charset     string
+}
+
+type lintColumn struct {
+	Name     string
+	Pos      lintPos
+	DataType string
+	Charset  string
+}
+
+type lintForeignKey struct {
+	Pos     lintPos
+	Columns []string
+}
+
+var (
+	alterTableAddRegex = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:ONLY\s+)?(?:IF\s+EXISTS\s+)?(` + identifierPattern + `)\s+`)
+	addClauseRegex     = regexp.MustCompile(`(?i)^ADD\s+(?:COLUMN\s+)?`)
+	constraintRegex    = regexp.MustCompile(`(?i)^CONSTRAINT\s+(?:` + identifierPattern + `\s+)?`)
+	primaryKeyRegex    = regexp.MustCompile(`(?i)^PRIMARY\s+KEY\b`)
+	foreignKeyRegex    = regexp.MustCompile(`(?i)^FOREIGN\s+KEY\b`)
+	indexItemRegex     = regexp.MustCompile(`(?i)^(?:UNIQUE|KEY|INDEX|FULLTEXT|SPATIAL)\b`)
+	otherItemRegex     = regexp.MustCompile(`(?i)^(?:CHECK|EXCLUDE|LIKE|PERIOD)\b`)
+	columnItemRegex    = regexp.MustCompile(`^(` + identifierPartPattern + `)\s+(\w+)`)
+	inlinePrimaryRegex = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\b`)
+	inlineUniqueRegex  = regexp.MustCompile(`(?i)\bUNIQUE\b`)
+	inlineRefRegex     = regexp.MustCompile(`(?i)\bREFERENCES\b`)
+	charsetRegex       = regexp.MustCompile(`(?i)\b(?:CHARACTER\s+SET|CHARSET)\s*=?\s*(\w+)`)
+	collateRegex       = regexp.MustCompile(`(?i)\bCOLLATE\s*=?\s*([a-z0-9]+)_`)
+)
+
+// floatTypes are the column types which must not store money since they are not exact.
+var floatTypes = map[string]struct{}{
+	"FLOAT":  {},
+	"FLOAT4": {},
+	"FLOAT8": {},
+	"DOUBLE": {},
+	"REAL":   {},
+}
+
+// splitSQLStatements splits the content of a schema file into statements.
+// Comments are removed while the line breaks in them are kept to report the positions.
+// The semicolons in the dollar-quoted strings of PostgreSQL and in the BEGIN ... END blocks of
+// the functions, the procedures and the triggers don't split the statements.
+func splitSQLStatements(file, content string) []sqlStatement {
+	var (
+		stmts  []sqlStatement
+		buf    strings.Builder
+		line   = 1
+		start  = 0
+		quote  rune
+		dollar string
+		depth  int
+	)
+	flush := func() {
+		if text := strings.TrimSpace(buf.String()); text != "" {
+			stmts = append(stmts, sqlStatement{Pos: lintPos{File: file, Line: start}, Text: text})
+		}
+		buf.Reset()
+		start = 0
+		depth = 0
+	}
+
+	runes := []rune(content)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		quoted := quote != 0 || dollar != ""
+		if !quoted && c == '-' && i+1 < len(runes) && runes[i+1] == '-' {
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+			continue
+		}
+		if !quoted && c == '/' && i+1 < len(runes) && runes[i+1] == '*' {
+			for i += 2; i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/'); i++ {
+				if runes[i] == '\n' {
+					line++
+					if start != 0 {
+						buf.WriteRune('\n')
+					}
+				}
+			}
+			i++
+			if start != 0 {
+				buf.WriteRune(' ')
+			}
+			continue
+		}
+
+		if start == 0 && !unicode.IsSpace(c) {
+			start = line
+		}
+		wordStart := i == 0 || !isIdentifierRune(runes[i-1])
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '\'' && i+1 < len(runes) {
+				buf.WriteRune(c)
+				i++
+				c = runes[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case dollar != "":
+			if string(runes[i:min(i+len([]rune(dollar)), len(runes))]) == dollar {
+				buf.WriteString(dollar)
+				i += len([]rune(dollar)) - 1
+				dollar = ""
+				continue
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$' && wordStart:
+			if tag := dollarQuoteTag(runes[i:]); tag != "" {
+				buf.WriteString(tag)
+				i += len([]rune(tag)) - 1
+				dollar = tag
+				continue
+			}
+		case (unicode.IsLetter(c) || c == '_') && wordStart:
+			word, next, nextEnd := sqlWord(runes, i)
+			switch strings.ToUpper(word) {
+			case "BEGIN":
+				// BEGIN also starts a transaction, which has no END.
+				if n := strings.ToUpper(next); n != "TRAN" && n != "TRANSACTION" && n != "WORK" &&
+					(depth > 0 || routineRegex.MatchString(strings.TrimSpace(buf.String()))) {
+					depth++
+				}
+			case "CASE":
+				if depth > 0 {
+					depth++
+				}
+			case "END":
+				switch strings.ToUpper(next) {
+				case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+				case "CASE":
+					// END CASE closes the CASE statement, the following CASE doesn't open another one.
+					depth = max(depth-1, 0)
+					word = string(runes[i:nextEnd])
+					line += strings.Count(word, "\n")
+				default:
+					depth = max(depth-1, 0)
+				}
+			}
+			buf.WriteString(word)
+			i += len([]rune(word)) - 1
+			continue
+		case c == ';' && depth == 0:
+			flush()
+			continue
+		}
+		if start != 0 {
+			buf.WriteRune(c)
+		}
+		if c == '\n' {
+			line++
+		}
+	}
+	flush()
+	return stmts
+}
+
+// routineRegex matches the beginning of the statements defining a function, a procedure, a trigger or an event,
+// whose BEGIN ... END blocks are kept in a statement.
+var routineRegex = regexp.MustCompile(`(?i)^CREATE\s+(?:OR\s+(?:REPLACE|ALTER)\s+)?(?:DEFINER\s*=\s*\S+\s+)?(?:CONSTRAINT\s+)?(?:FUNCTION|PROC|PROCEDURE|TRIGGER|EVENT)\b`)
+
+// isIdentifierRune returns true if the given rune can be a part of an unquoted identifier.
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// sqlWord returns the word starting at the given offset, the word following it and the offset where the following word ends.
+// The following word is empty when the word is followed by something else, e.g. a semicolon.
+func sqlWord(runes []rune, i int) (string, string, int) {
+	end := i
+	for end < len(runes) && isIdentifierRune(runes[end]) {
+		end++
+	}
+	next := end
+	for next < len(runes) && unicode.IsSpace(runes[next]) {
+		next++
+	}
+	nextEnd := next
+	for nextEnd < len(runes) && isIdentifierRune(runes[nextEnd]) {
+		nextEnd++
+	}
+	return string(runes[i:end]), string(runes[next:nextEnd]), nextEnd
+}
+
+// dollarQuoteTag returns the tag such as $$ or $body$ opening a dollar-quoted string of PostgreSQL
+// at the beginning of the given runes, or an empty string if they don't start with it.
+func dollarQuoteTag(runes []rune) string {
+	end := 1
+	for end < len(runes) && runes[end] != '$' && (unicode.IsLetter(runes[end]) || runes[end] == '_' || (end > 1 && unicode.IsDigit(runes[end]))) {
+		end++
+	}
+	if end >= len(runes) || runes[end] != '$' {
+		return ""
+	}
+	return string(runes[:end+1])
+}
+
+// parseLintTables returns the tables defined by CREATE TABLE statements
+// with the indexes and the constraints added by CREATE INDEX and ALTER TABLE statements.
+func parseLintTables(stmts []sqlStatement) []*lintTable {
+	var tables []*lintTable
+	find := func(name string) *lintTable {
+		for _, t := range tables {
+			if t.Name == name || unqualifiedName(t.Name) == unqualifiedName(name) {
+				return t
+			}
+		}
+		return nil
+	}
+
+	for _, stmt := range stmts {
+		if m := createTableRegex.FindStringSubmatchIndex(stmt.Text); m != nil {
+			if t := parseCreateTable(stmt, unquoteIdentifier(stmt.Text[m[2]:m[3]]), m[1]); t != nil {
+				tables = append(tables, t)
+			}
+			continue
+		}
+		if m := createIndexRegex.FindStringSubmatchIndex(stmt.Text); m != nil {
+			if t := find(unquoteIdentifier(stmt.Text[m[4]:m[5]])); t != nil {
+				if cols := columnList(stmt.Text[m[1]:]); len(cols) > 0 {
+					t.Indexes = append(t.Indexes, cols)
+				}
+			}
+			continue
+		}
+		if m := alterTableAddRegex.FindStringSubmatchIndex(stmt.Text); m != nil {
+			t := find(unquoteIdentifier(stmt.Text[m[2]:m[3]]))
+			if t == nil {
+				continue
+			}
+			for _, clause := range splitTopLevel(stmt.Text, m[1], len(stmt.Text)) {
+				text := stmt.Text[clause:]
+				if loc := addClauseRegex.FindStringIndex(text); loc != nil {
+					end := clause + len(topLevelItem(text))
+					addTableItem(t, stmt, clause+loc[1], end)
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// parseCreateTable parses the table definition following the given offset of the CREATE TABLE statement.
+// It returns nil if the statement does not define the columns, e.g. CREATE TABLE ... AS SELECT.
+func parseCreateTable(stmt sqlStatement, name string, offset int) *lintTable {
+	open := offset + strings.IndexByte(stmt.Text[offset:], '(')
+	if open < offset || strings.TrimSpace(stmt.Text[offset:open]) != "" {
+		return nil
+	}
+	end := closingParen(stmt.Text, open)
+	if end < 0 {
+		return nil
+	}
+
+	t := &lintTable{Name: name, Pos: stmt.Pos}
+	for _, item := range splitTopLevel(stmt.Text, open+1, end) {
+		addTableItem(t, stmt, item, item+len(topLevelItem(stmt.Text[item:end])))
+	}
+	t.Charset = charsetOf(stmt.Text[end+1:])
+	return t
+}
+
+// addTableItem adds the column, the index or the constraint defined between the given offsets to the table.
+func addTableItem(t *lintTable, stmt sqlStatement, start, end int) {
+	item := strings.TrimSpace(stmt.Text[start:end])
+	pos := stmt.posAt(start)
+	if loc := constraintRegex.FindStringIndex(item); loc != nil {
+		item = item[loc[1]:]
+	}
+
+	switch {
+	case primaryKeyRegex.MatchString(item):
+		t.PrimaryKey = true
+		if cols := columnList(item); len(cols) > 0 {
+			t.Indexes = append(t.Indexes, cols)
+		}
+	case foreignKeyRegex.MatchString(item):
+		if cols := columnList(item); len(cols) > 0 {
+			t.ForeignKeys = append(t.ForeignKeys, lintForeignKey{Pos: pos, Columns: cols})
+		}
+	case indexItemRegex.MatchString(item):
+		if cols := columnList(item); len(cols) > 0 {
+			t.Indexes = append(t.Indexes, cols)
+		}
+	case otherItemRegex.MatchString(item):
+	default:
+		m := columnItemRegex.FindStringSubmatch(item)
+		if m == nil {
+			return
+		}
+		col := lintColumn{Name: unquoteIdentifier(m[1]), Pos: pos, DataType: strings.ToUpper(m[2]), Charset: charsetOf(item)}
+		t.Columns = append(t.Columns, col)
+		if inlinePrimaryRegex.MatchString(item) {
+			t.PrimaryKey = true
+			t.Indexes = append(t.Indexes, []string{col.Name})
+		} else if inlineUniqueRegex.MatchString(item) {
+			t.Indexes = append(t.Indexes, []string{col.Name})
+		}
+		if inlineRefRegex.MatchString(item) {
+			t.ForeignKeys = append(t.ForeignKeys, lintForeignKey{Pos: pos, Columns: []string{col.Name}})
+		}
+	}
+}
+
+// charsetOf returns the charset specified in the given definition, or the charset of the collation if only it is specified.
+func charsetOf(def string) string {
+	if m := charsetRegex.FindStringSubmatch(def); m != nil {
+		return m[1]
+	}
+	if m := collateRegex.FindStringSubmatch(def); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// columnList returns the names of the columns in the first parentheses of the given text.
+// The prefix lengths and the orders of the index columns are removed.
+func columnList(text string) []string {
+	open := strings.IndexByte(text, '(')
+	if open < 0 {
+		return nil
+	}
+	end := closingParen(text, open)
+	if end < 0 {
+		return nil
+	}
+	var cols []string
+	for _, item := range splitTopLevel(text, open+1, end) {
+		if part := identifierPartRegex.FindString(topLevelItem(text[item:end])); part != "" {
+			cols = append(cols, unquoteIdentifier(part))
+		}
+	}
+	return cols
+}
+
+// closingParen returns the offset of the parenthesis closing the one at the given offset, or -1 if it is not closed.
+func closingParen(text string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel returns the offsets of the items separated by the commas outside parentheses between the given offsets.
+// Each offset points to the first non-space character of the item.
+func splitTopLevel(text string, start, end int) []int {
+	var offsets []int
+	next := start
+	add := func(from, to int) {
+		for from < to && unicode.IsSpace(rune(text[from])) {
+			from++
+		}
+		if from < to {
+			offsets = append(offsets, from)
+		}
+	}
+	depth := 0
+	var quote byte
+	for i := start; i < end; i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(next, i)
+			next = i + 1
+		}
+	}
+	add(next, end)
+	return offsets
+}
+
+// topLevelItem returns the given text up to the first comma outside parentheses.
+func topLevelItem(text string) string {
+	offsets := splitTopLevel(text, 0, len(text))
+	if len(offsets) < 2 {
+		return text
+	}
+	return text[:strings.LastIndexByte(text[:offsets[1]], ',')]
+}
+
+// unqualifiedName returns the name without the schema.
+func unqualifiedName(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// lintSchema checks the tables against the enabled rules and returns the violations
+// sorted by the positions in the order of the given files.
+func lintSchema(cfg config.LintConfig, files []string, tables []*lintTable) []lintViolation {
+	var violations []lintViolation
+	report := func(rule string, severity config.LintSeverity, pos lintPos, format string, args ...any) {
+		violations = append(violations, lintViolation{Pos: pos, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var tableNameRegex, columnNameRegex, moneyColumnRegex *regexp.Regexp
+	if cfg.TableName.Severity.Enabled() {
+		tableNameRegex = regexp.MustCompile(cfg.TableName.Pattern)
+	}
+	if cfg.ColumnName.Severity.Enabled() {
+		columnNameRegex = regexp.MustCompile(cfg.ColumnName.Pattern)
+	}
+	if cfg.MoneyColumn.Severity.Enabled() {
+		pattern := cfg.MoneyColumn.Pattern
+		if pattern == "" {
+			pattern = config.DefaultMoneyColumnPattern
+		}
+		moneyColumnRegex = regexp.MustCompile(pattern)
+	}
+	charset := cfg.Charset.CharsetOrDefault()
+
+	for _, t := range tables {
+		if cfg.PrimaryKey.Severity.Enabled() && !t.PrimaryKey {
+			report("primaryKey", cfg.PrimaryKey.Severity, t.Pos, "table %s has no primary key", t.Name)
+		}
+		if tableNameRegex != nil && !tableNameRegex.MatchString(unqualifiedName(t.Name)) {
+			report("tableName", cfg.TableName.Severity, t.Pos, "table name %s does not match %s", t.Name, cfg.TableName.Pattern)
+		}
+		if cfg.Charset.Severity.Enabled() {
+			if t.Charset == "" {
+				report("charset", cfg.Charset.Severity, t.Pos, "table %s does not specify the charset, expected %s", t.Name, charset)
+			} else if !strings.EqualFold(t.Charset, charset) {
+				report("charset", cfg.Charset.Severity, t.Pos, "table %s uses charset %s, expected %s", t.Name, t.Charset, charset)
+			}
+		}
+
+		for _, c := range t.Columns {
+			if columnNameRegex != nil && !columnNameRegex.MatchString(c.Name) {
+				report("columnName", cfg.ColumnName.Severity, c.Pos, "column name %s.%s does not match %s", t.Name, c.Name, cfg.ColumnName.Pattern)
+			}
+			if _, ok := floatTypes[c.DataType]; ok && moneyColumnRegex != nil && moneyColumnRegex.MatchString(c.Name) {
+				report("moneyColumn", cfg.MoneyColumn.Severity, c.Pos, "column %s.%s stores money in %s, use DECIMAL instead", t.Name, c.Name, c.DataType)
+			}
+			if cfg.Charset.Severity.Enabled() && c.Charset != "" && !strings.EqualFold(c.Charset, charset) {
+				report("charset", cfg.Charset.Severity, c.Pos, "column %s.%s uses charset %s, expected %s", t.Name, c.Name, c.Charset, charset)
+			}
+		}
+
+		if cfg.ForeignKeyIndex.Severity.Enabled() {
+			for _, fk := range t.ForeignKeys {
+				if !hasIndexPrefix(t.Indexes, fk.Columns) {
+					report("foreignKeyIndex", cfg.ForeignKeyIndex.Severity, fk.Pos, "foreign key (%s) of table %s has no index starting with its columns", strings.Join(fk.Columns, ", "), t.Name)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i].Pos, violations[j].Pos
+		if a.File != b.File {
+			return slices.Index(files, a.File) < slices.Index(files, b.File)
+		}
+		return a.Line < b.Line
+	})
+	return violations
+}
+
+// hasIndexPrefix returns true if any of the indexes starts with the given columns.
+func hasIndexPrefix(indexes [][]string, cols []string) bool {
+	for _, idx := range indexes {
+		if len(idx) >= len(cols) && slicesEqualFold(idx[:len(cols)], cols) {
+			return true
+		}
+	}
+	return false
+}
+
+func slicesEqualFold(a, b []string) bool {
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,325 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+const lintSchemaSQL = `-- users of the service
+CREATE TABLE users (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  name VARCHAR(255) NOT NULL COMMENT 'the name; shown on the profile',
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+/* orders placed by
+   the users */
+CREATE TABLE Orders (
+  id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  totalPrice FLOAT NOT NULL,
+  note TEXT CHARACTER SET latin1,
+  CONSTRAINT fk_orders_user FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE payments (
+  order_id BIGINT NOT NULL REFERENCES Orders (id),
+  amount DECIMAL(10, 2) NOT NULL,
+  fee DOUBLE NOT NULL
+) ENGINE=InnoDB COLLATE=utf8mb4_bin;
+
+CREATE INDEX idx_payments_order_id ON payments (order_id DESC);
+`
+
+func TestSplitSQLStatements(t *testing.T) {
+	t.Parallel()
+
+	stmts := splitSQLStatements("schema.sql", lintSchemaSQL)
+	require.Len(t, stmts, 4)
+
+	lines := make([]int, 0, len(stmts))
+	for _, s := range stmts {
+		assert.Equal(t, "schema.sql", s.Pos.File)
+		lines = append(lines, s.Pos.Line)
+	}
+	assert.Equal(t, []int{2, 10, 18, 24}, lines)
+	assert.Contains(t, stmts[0].Text, "'the name; shown on the profile'")
+	assert.NotContains(t, stmts[1].Text, "orders placed by")
+}
+
+func TestSplitSQLStatements_Blocks(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		schema   string
+		expected []int
+	}{
+		{
+			name: "dollar-quoted function body",
+			schema: `CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+`,
+			expected: []int{1, 8},
+		},
+		{
+			name: "dollar-quoted string with a tag",
+			schema: `CREATE FUNCTION hello() RETURNS text AS $body$ SELECT 'a;b' || $$;$$ $body$ LANGUAGE sql;
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+`,
+			expected: []int{1, 2},
+		},
+		{
+			name: "procedure with nested blocks",
+			schema: `CREATE PROCEDURE archive(IN days INT)
+BEGIN
+  IF days > 0 THEN
+    DELETE FROM orders WHERE created_at < NOW() - INTERVAL days DAY;
+  END IF;
+  CASE days
+    WHEN 0 THEN SELECT 1;
+    ELSE BEGIN SELECT CASE WHEN days > 1 THEN 2 END; END;
+  END
+  CASE;
+  SELECT 3;
+END;
+CREATE TABLE orders (id BIGINT PRIMARY KEY);
+`,
+			expected: []int{1, 13},
+		},
+		{
+			name: "trigger",
+			schema: `CREATE TRIGGER touch AFTER UPDATE ON users
+BEGIN
+  UPDATE users SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+CREATE TABLE orders (id BIGINT PRIMARY KEY);
+`,
+			expected: []int{1, 5},
+		},
+		{
+			name: "transaction",
+			schema: `BEGIN;
+CREATE TABLE users (id BIGINT PRIMARY KEY, begin DATE, end DATE);
+COMMIT;
+`,
+			expected: []int{1, 2, 3},
+		},
+		{
+			name:     "dollar in an identifier",
+			schema:   "CREATE TABLE users (id BIGINT PRIMARY KEY, price$1 INT);\nCREATE TABLE orders (id BIGINT PRIMARY KEY);\n",
+			expected: []int{1, 2},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stmts := splitSQLStatements("schema.sql", tc.schema)
+			lines := make([]int, 0, len(stmts))
+			for _, s := range stmts {
+				lines = append(lines, s.Pos.Line)
+			}
+			assert.Equal(t, tc.expected, lines)
+		})
+	}
+}
+
+func TestParseLintTables(t *testing.T) {
+	t.Parallel()
+
+	tables := parseLintTables(splitSQLStatements("schema.sql", lintSchemaSQL))
+	require.Len(t, tables, 3)
+
+	users, orders, payments := tables[0], tables[1], tables[2]
+
+	assert.Equal(t, "users", users.Name)
+	assert.True(t, users.PrimaryKey)
+	assert.Equal(t, [][]string{{"id"}}, users.Indexes)
+	assert.Equal(t, "utf8mb4", users.Charset)
+
+	assert.Equal(t, "Orders", orders.Name)
+	assert.False(t, orders.PrimaryKey)
+	require.Len(t, orders.Columns, 4)
+	assert.Equal(t, lintColumn{Name: "totalPrice", Pos: lintPos{File: "schema.sql", Line: 13}, DataType: "FLOAT"}, orders.Columns[2])
+	assert.Equal(t, "latin1", orders.Columns[3].Charset)
+	assert.Equal(t, []lintForeignKey{{Pos: lintPos{File: "schema.sql", Line: 15}, Columns: []string{"user_id"}}}, orders.ForeignKeys)
+
+	assert.Equal(t, "utf8mb4", payments.Charset)
+	assert.Equal(t, []lintForeignKey{{Pos: lintPos{File: "schema.sql", Line: 19}, Columns: []string{"order_id"}}}, payments.ForeignKeys)
+	assert.Equal(t, [][]string{{"order_id"}}, payments.Indexes)
+}
+
+func TestParseLintTables_AlterTable(t *testing.T) {
+	t.Parallel()
+
+	const schema = `CREATE TABLE public.users (
+    id bigint NOT NULL,
+    team_id bigint NOT NULL
+);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id),
+    ADD CONSTRAINT users_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id);
+`
+	tables := parseLintTables(splitSQLStatements("schema.sql", schema))
+	require.Len(t, tables, 1)
+	assert.True(t, tables[0].PrimaryKey)
+	assert.Equal(t, [][]string{{"id"}}, tables[0].Indexes)
+	assert.Equal(t, []lintForeignKey{{Pos: lintPos{File: "schema.sql", Line: 8}, Columns: []string{"team_id"}}}, tables[0].ForeignKeys)
+}
+
+func TestLintSchema(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		cfg      config.LintConfig
+		expected []string
+	}{
+		{
+			name: "primary key",
+			cfg:  config.LintConfig{PrimaryKey: config.LintRule{Severity: config.LintSeverityError}},
+			expected: []string{
+				"schema.sql:10: table Orders has no primary key [primaryKey]",
+				"schema.sql:18: table payments has no primary key [primaryKey]",
+			},
+		},
+		{
+			name: "naming conventions",
+			cfg: config.LintConfig{
+				TableName:  config.LintPatternRule{Severity: config.LintSeverityError, Pattern: "^[a-z][a-z0-9_]*$"},
+				ColumnName: config.LintPatternRule{Severity: config.LintSeverityWarning, Pattern: "^[a-z][a-z0-9_]*$"},
+			},
+			expected: []string{
+				"schema.sql:10: table name Orders does not match ^[a-z][a-z0-9_]*$ [tableName]",
+				"schema.sql:13: column name Orders.totalPrice does not match ^[a-z][a-z0-9_]*$ [columnName]",
+			},
+		},
+		{
+			name: "money columns with the default pattern",
+			cfg:  config.LintConfig{MoneyColumn: config.LintPatternRule{Severity: config.LintSeverityError}},
+			expected: []string{
+				"schema.sql:13: column Orders.totalPrice stores money in FLOAT, use DECIMAL instead [moneyColumn]",
+				"schema.sql:21: column payments.fee stores money in DOUBLE, use DECIMAL instead [moneyColumn]",
+			},
+		},
+		{
+			name: "charset",
+			cfg:  config.LintConfig{Charset: config.LintCharsetRule{Severity: config.LintSeverityError}},
+			expected: []string{
+				"schema.sql:10: table Orders uses charset latin1, expected utf8mb4 [charset]",
+				"schema.sql:14: column Orders.note uses charset latin1, expected utf8mb4 [charset]",
+			},
+		},
+		{
+			name: "foreign key index",
+			cfg:  config.LintConfig{ForeignKeyIndex: config.LintRule{Severity: config.LintSeverityError}},
+			expected: []string{
+				"schema.sql:15: foreign key (user_id) of table Orders has no index starting with its columns [foreignKeyIndex]",
+			},
+		},
+	}
+
+	tables := parseLintTables(splitSQLStatements("schema.sql", lintSchemaSQL))
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, v := range lintSchema(tc.cfg, []string{"schema.sql"}, tables) {
+				got = append(got, v.String())
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestPlugin_executeLintStage(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		lint     config.LintConfig
+		expected sdk.StageStatus
+	}{
+		{
+			name:     "fails on errors",
+			lint:     config.LintConfig{PrimaryKey: config.LintRule{Severity: config.LintSeverityError}},
+			expected: sdk.StageStatusFailure,
+		},
+		{
+			name:     "succeeds with warnings",
+			lint:     config.LintConfig{PrimaryKey: config.LintRule{Severity: config.LintSeverityWarning}},
+			expected: sdk.StageStatusSuccess,
+		},
+		{
+			name:     "succeeds without violations",
+			lint:     config.LintConfig{TableName: config.LintPatternRule{Severity: config.LintSeverityError, Pattern: "^[A-Za-z]+$"}},
+			expected: sdk.StageStatusSuccess,
+		},
+		{
+			name:     "fails without lint rules",
+			expected: sdk.StageStatusFailure,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			appDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(appDir, "schema.sql"), []byte(lintSchemaSQL), 0644))
+
+			ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: appDir,
+				CommitHash:           "0123456789",
+				ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+					Spec: &config.ApplicationConfigSpec{Lint: tc.lint},
+				},
+			}
+			input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+				Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+					StageName:               sqldefStageLint,
+					RunningDeploymentSource: ds,
+					TargetDeploymentSource:  ds,
+				},
+				Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+			}
+
+			plugin := &Plugin{}
+			assert.Equal(t, tc.expected, plugin.executeLintStage(context.Background(), input))
+		})
+	}
+}
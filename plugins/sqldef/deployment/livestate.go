@@ -0,0 +1,218 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
+)
+
+var _ sdk.LivestatePlugin[config.Config, config.DeployTargetConfig, config.ApplicationConfigSpec] = (*Plugin)(nil)
+
+const (
+	resourceTypeTable = "table"
+	resourceTypeView  = "view"
+	resourceTypeIndex = "index"
+)
+
+const (
+	// identifierPartPattern matches a part of an identifier which may be quoted with backquotes, double quotes or brackets.
+	identifierPartPattern = "(?:`[^`]+`|\"[^\"]+\"|\\[[^\\]]+\\]|[\\w$]+)"
+	// identifierPattern matches an identifier which may be qualified with the schema, e.g. "public"."users".
+	identifierPattern = identifierPartPattern + "(?:\\." + identifierPartPattern + ")?"
+)
+
+var (
+	identifierPartRegex = regexp.MustCompile(identifierPartPattern)
+	createTableRegex    = regexp.MustCompile(`(?i)^CREATE\s+(?:TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + identifierPattern + `)`)
+	// mysqldef exports views with their options, e.g. "CREATE ALGORITHM=UNDEFINED DEFINER=... VIEW".
+	createViewRegex  = regexp.MustCompile(`(?i)^CREATE\s+(?:.*?\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + identifierPattern + `)`)
+	createIndexRegex = regexp.MustCompile(`(?i)^CREATE\s+(?:UNIQUE\s+)?(?:(?:NON)?CLUSTERED\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + identifierPattern + `)\s+ON\s+(?:ONLY\s+)?(` + identifierPattern + `)`)
+	// MySQL exports the indexes in the definition of the table.
+	inlineIndexRegex = regexp.MustCompile(`(?i)[(,]\s*(?:UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?(?:KEY|INDEX)\s+(` + identifierPattern + `)\s*\(`)
+)
+
+// GetLivestate returns the tables, views and indexes of each deploy target as the resources,
+// and compares the exported schema with the desired schema files to detect the drift.
+func (p *Plugin) GetLivestate(
+	ctx context.Context,
+	cfg *config.Config,
+	dts []*sdk.DeployTarget[config.DeployTargetConfig],
+	input *sdk.GetLivestateInput[config.ApplicationConfigSpec],
+) (*sdk.GetLivestateResponse, error) {
+	toolRegistry := toolRegistryPkg.NewRegistry(input.Client.ToolRegistry())
+	lp := zapLogPersister{logger: input.Logger}
+
+	ds := input.Request.DeploymentSource
+	schemaFiles, schemaErr := resolveSchemaFiles(ds.ApplicationDirectory, applicationSpec(ds))
+	if schemaErr != nil {
+		input.Logger.Error("Failed to find schema files", zap.Error(schemaErr))
+	}
+
+	var (
+		resources []sdk.ResourceState
+		pending   = make(map[string][]string, len(dts))
+		syncErrs  []string
+	)
+	for _, dt := range dts {
+		if err := dt.Config.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config of deploy target %s: %w", dt.Name, err)
+		}
+
+		execPath, err := toolRegistry.DownloadBinary(ctx, dt.Config.DbType, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sqldef tool for deploy target %s: %w", dt.Name, err)
+		}
+		target := sqldefTarget(dt.Config, execPath, applicationSpec(ds))
+
+		schema, err := p.Sqldef.ShowCurrentSchema(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export the schema of deploy target %s: %w", dt.Name, err)
+		}
+		resources = append(resources, schemaResources(dt.Name, schema)...)
+
+		if schemaErr != nil {
+			continue
+		}
+		result, err := p.Sqldef.Execute(ctx, lp, target, schemaFiles, true)
+		if err != nil {
+			input.Logger.Error("Failed to compare the schema", zap.String("deploy-target", dt.Name), zap.Error(err))
+			syncErrs = append(syncErrs, fmt.Sprintf("Deploy Target [%s]: %v", dt.Name, err))
+			continue
+		}
+		if !result.NoChanges() {
+			pending[dt.Name] = result.Statements
+		}
+	}
+
+	var syncState sdk.ApplicationSyncState
+	switch {
+	case schemaErr != nil:
+		syncState = sdk.ApplicationSyncState{
+			Status:      sdk.ApplicationSyncStateInvalidConfig,
+			ShortReason: "Failed to find schema files",
+			Reason:      schemaErr.Error(),
+		}
+	case len(syncErrs) > 0:
+		syncState = sdk.ApplicationSyncState{
+			Status:      sdk.ApplicationSyncStateUnknown,
+			ShortReason: "Failed to compare the schema with the desired one",
+			Reason:      strings.Join(syncErrs, "\n"),
+		}
+	default:
+		syncState = schemaSyncState(dts, pending, ds.CommitHash)
+	}
+
+	return &sdk.GetLivestateResponse{
+		LiveState: sdk.ApplicationLiveState{
+			Resources: resources,
+		},
+		SyncState: syncState,
+	}, nil
+}
+
+// schemaResources returns the tables, views and indexes found in the schema exported from the deploy target.
+// Indexes are the children of the table they belong to.
+func schemaResources(target, schema string) []sdk.ResourceState {
+	var resources []sdk.ResourceState
+	add := func(resourceType, name string, parents ...string) string {
+		id := fmt.Sprintf("%s/%s/%s", target, resourceType, name)
+		resources = append(resources, sdk.ResourceState{
+			ID:           id,
+			ParentIDs:    parents,
+			Name:         name,
+			ResourceType: resourceType,
+			HealthStatus: sdk.ResourceHealthStateHealthy,
+			DeployTarget: target,
+		})
+		return id
+	}
+	tableID := func(table string) string {
+		return fmt.Sprintf("%s/%s/%s", target, resourceTypeTable, table)
+	}
+
+	for _, stmt := range provider.SplitStatements(schema) {
+		if m := createTableRegex.FindStringSubmatch(stmt); m != nil {
+			id := add(resourceTypeTable, unquoteIdentifier(m[1]))
+			for _, im := range inlineIndexRegex.FindAllStringSubmatch(stmt, -1) {
+				add(resourceTypeIndex, unquoteIdentifier(im[1]), id)
+			}
+			continue
+		}
+		if m := createIndexRegex.FindStringSubmatch(stmt); m != nil {
+			add(resourceTypeIndex, unquoteIdentifier(m[1]), tableID(unquoteIdentifier(m[2])))
+			continue
+		}
+		if m := createViewRegex.FindStringSubmatch(stmt); m != nil {
+			add(resourceTypeView, unquoteIdentifier(m[1]))
+		}
+	}
+	return resources
+}
+
+// unquoteIdentifier removes the quotes of each part of the given identifier.
+func unquoteIdentifier(ident string) string {
+	parts := identifierPartRegex.FindAllString(ident, -1)
+	for i, part := range parts {
+		parts[i] = strings.Trim(part, "`\"[]")
+	}
+	return strings.Join(parts, ".")
+}
+
+// schemaSyncState returns the sync state from the statements sqldef would execute against each deploy target.
+func schemaSyncState(dts []*sdk.DeployTarget[config.DeployTargetConfig], pending map[string][]string, commit string) sdk.ApplicationSyncState {
+	if len(pending) == 0 {
+		return sdk.ApplicationSyncState{
+			Status: sdk.ApplicationSyncStateSynced,
+		}
+	}
+
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+
+	var (
+		b     strings.Builder
+		total int
+	)
+	fmt.Fprintf(&b, "Statements to apply the schema defined in Git at commit %s to the live database:\n", commit)
+	for _, dt := range dts {
+		stmts, ok := pending[dt.Name]
+		if !ok {
+			continue
+		}
+		total += len(stmts)
+		fmt.Fprintf(&b, "\n-- Deploy Target [%s]\n", dt.Name)
+		for _, stmt := range stmts {
+			b.WriteString(stmt)
+			b.WriteString("\n")
+		}
+	}
+
+	return sdk.ApplicationSyncState{
+		Status:      sdk.ApplicationSyncStateOutOfSync,
+		ShortReason: fmt.Sprintf("There are %d pending statement(s) in %d deploy target(s)", total, len(pending)),
+		Reason:      b.String(),
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+func TestSchemaResources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		schema   string
+		expected []sdk.ResourceState
+	}{
+		{
+			name: "mysql",
+			schema: "CREATE TABLE `users` (\n" +
+				"  `id` int NOT NULL,\n" +
+				"  `name` varchar(255) NOT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE KEY `idx_name` (`name`)\n" +
+				") ENGINE=InnoDB;\n" +
+				"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `user_names` AS select `users`.`name` from `users`;\n",
+			expected: []sdk.ResourceState{
+				{ID: "db/table/users", Name: "users", ResourceType: "table", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+				{ID: "db/index/idx_name", ParentIDs: []string{"db/table/users"}, Name: "idx_name", ResourceType: "index", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+				{ID: "db/view/user_names", Name: "user_names", ResourceType: "view", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+			},
+		},
+		{
+			name: "postgres",
+			schema: "CREATE TABLE \"public\".\"users\" (\n" +
+				"    \"id\" integer NOT NULL,\n" +
+				"    \"key\" text NOT NULL\n" +
+				");\n" +
+				"CREATE UNIQUE INDEX idx_key ON public.users USING btree (key);\n" +
+				"CREATE VIEW \"public\".\"user_keys\" AS SELECT key FROM users;\n",
+			expected: []sdk.ResourceState{
+				{ID: "db/table/public.users", Name: "public.users", ResourceType: "table", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+				{ID: "db/index/idx_key", ParentIDs: []string{"db/table/public.users"}, Name: "idx_key", ResourceType: "index", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+				{ID: "db/view/public.user_keys", Name: "public.user_keys", ResourceType: "view", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+			},
+		},
+		{
+			name: "mssql",
+			schema: "CREATE TABLE [dbo].[users] (\n" +
+				"    [id] int NOT NULL\n" +
+				");\n" +
+				"CREATE NONCLUSTERED INDEX [idx_id] ON [dbo].[users] ([id]);\n",
+			expected: []sdk.ResourceState{
+				{ID: "db/table/dbo.users", Name: "dbo.users", ResourceType: "table", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+				{ID: "db/index/idx_id", ParentIDs: []string{"db/table/dbo.users"}, Name: "idx_id", ResourceType: "index", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "db"},
+			},
+		},
+		{
+			name:     "empty",
+			schema:   "",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, schemaResources("db", tt.schema))
+		})
+	}
+}
+
+func newLivestateInput(t *testing.T) *sdk.GetLivestateInput[config.ApplicationConfigSpec] {
+	t.Helper()
+	return &sdk.GetLivestateInput[config.ApplicationConfigSpec]{
+		Request: sdk.GetLivestateRequest[config.ApplicationConfigSpec]{
+			ApplicationID: "app-id",
+			DeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: filepath.Join("testdata", "app"),
+				CommitHash:           "0123456789",
+			},
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+		Logger: zap.NewNop(),
+	}
+}
+
+func TestPlugin_GetLivestate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	schemaFiles := []string{filepath.Join("testdata", "app", "schema.sql")}
+	targetConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/test.db",
+	}
+	dts := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "sqlite", Config: targetConfig},
+	}
+
+	tests := []struct {
+		name           string
+		dryRunOutput   string
+		dryRunErr      error
+		expectedStatus sdk.ApplicationSyncStatus
+		expectedShort  string
+		expectedReason string
+	}{
+		{
+			name:           "synced",
+			dryRunOutput:   "-- Nothing is modified --\n",
+			expectedStatus: sdk.ApplicationSyncStateSynced,
+		},
+		{
+			name:           "out of sync",
+			dryRunOutput:   "-- dry run --\nBEGIN;\nALTER TABLE users ADD COLUMN email TEXT;\nCOMMIT;\n",
+			expectedStatus: sdk.ApplicationSyncStateOutOfSync,
+			expectedShort:  "There are 1 pending statement(s) in 1 deploy target(s)",
+			expectedReason: "Statements to apply the schema defined in Git at commit 0123456 to the live database:\n\n-- Deploy Target [sqlite]\nALTER TABLE users ADD COLUMN email TEXT;\n",
+		},
+		{
+			name:           "dry run failed",
+			dryRunErr:      errors.New("database is locked"),
+			expectedStatus: sdk.ApplicationSyncStateUnknown,
+			expectedShort:  "Failed to compare the schema with the desired one",
+			expectedReason: "Deploy Target [sqlite]: database is locked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockSqldef := &MockSqldefProvider{}
+			mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("CREATE TABLE users (id INTEGER PRIMARY KEY);\n", nil)
+			mockSqldef.On("Execute", ctx, targetConfig, schemaFiles, true).Return(tt.dryRunOutput, tt.dryRunErr)
+
+			plugin := createPluginWithMockSqldef(mockSqldef)
+			resp, err := plugin.GetLivestate(ctx, nil, dts, newLivestateInput(t))
+			require.NoError(t, err)
+
+			require.Len(t, resp.LiveState.Resources, 1)
+			assert.Equal(t, "sqlite/table/users", resp.LiveState.Resources[0].ID)
+			assert.Equal(t, tt.expectedStatus, resp.SyncState.Status)
+			assert.Equal(t, tt.expectedShort, resp.SyncState.ShortReason)
+			assert.Equal(t, tt.expectedReason, resp.SyncState.Reason)
+			mockSqldef.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPlugin_GetLivestate_ExportFailed(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	targetConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/test.db",
+	}
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", errors.New("unable to open database file"))
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	_, err := plugin.GetLivestate(ctx, nil, []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "sqlite", Config: targetConfig},
+	}, newLivestateInput(t))
+	assert.ErrorContains(t, err, "failed to export the schema of deploy target sqlite: unable to open database file")
+}
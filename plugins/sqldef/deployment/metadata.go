@@ -0,0 +1,42 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+)
+
+// metadataStore stores the metadata shared among the stages of a deployment.
+// It is implemented by *sdk.Client.
+type metadataStore interface {
+	GetDeploymentPluginMetadata(ctx context.Context, key string) (string, bool, error)
+	PutDeploymentPluginMetadata(ctx context.Context, key, value string) error
+}
+
+// objectStore stores the objects of the application shared across deployments.
+// It is implemented by *sdk.Client.
+type objectStore interface {
+	PutApplicationSharedObject(ctx context.Context, key string, object []byte) error
+}
+
+// exportedSchemaKey returns the key of the application object storing the schema exported from the given deploy target.
+func exportedSchemaKey(target string) string {
+	return "exported-schema/" + target + ".sql"
+}
+
+// preApplySchemaKey returns the metadata key of the schema exported before applying to the given deploy target.
+func preApplySchemaKey(target string) string {
+	return "pre-apply-schema/" + target
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+var alterTableStmtRegex = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(` + identifierPattern + `)\s+(.+?)\s*;?$`)
+
+// tableStmtRegexes match the statements other than ALTER TABLE which change a table, capturing the table last.
+var tableStmtRegexes = []*regexp.Regexp{
+	createTableRegex,
+	createIndexRegex,
+	regexp.MustCompile(`(?i)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(` + identifierPattern + `)`),
+	regexp.MustCompile(`(?i)^DROP\s+INDEX\s+` + identifierPattern + `\s+ON\s+(` + identifierPattern + `)`),
+	regexp.MustCompile(`(?is)^CREATE\s+(?:.*?\s+)?TRIGGER\s+.+?\s+ON\s+(` + identifierPattern + `)`),
+}
+
+// onlineAlter is the ALTER TABLE clauses of a table which are run with gh-ost instead of sqldef.
+type onlineAlter struct {
+	Table string
+	Alter string
+}
+
+// onlineAlters returns the ALTER TABLE clauses of the deploy target to be run with gh-ost
+// when onlineSchemaChange is configured, and logs them.
+func (p *Plugin) onlineAlters(ctx context.Context, lp sdk.StageLogPersister, dt *sdk.DeployTarget[config.DeployTargetConfig], spec *config.ApplicationConfigSpec, stmts []string) ([]onlineAlter, error) {
+	if spec == nil || !spec.OnlineSchemaChange.Enabled() {
+		return nil, nil
+	}
+	if dt.Config.DbType != config.DBTypeMySQL {
+		lp.Infof("Deploy Target [%s]: onlineSchemaChange is ignored since it is only supported for MySQL", dt.Name)
+		return nil, nil
+	}
+
+	alters, err := p.planOnlineAlters(ctx, dt.Config, spec.OnlineSchemaChange, stmts)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alters {
+		lp.Infof("Deploy Target [%s]: table %s will be altered online with gh-ost: %s", dt.Name, a.Table, a.Alter)
+	}
+	return alters, nil
+}
+
+// planOnlineAlters returns the ALTER TABLE clauses in the given statements to be run with gh-ost.
+// The clauses of the same table are combined so that gh-ost copies the table only once.
+// Since sqldef skips the tables altered with gh-ost, it fails when such a table has other changes.
+func (p *Plugin) planOnlineAlters(ctx context.Context, cfg config.DeployTargetConfig, osc config.OnlineSchemaChangeConfig, stmts []string) ([]onlineAlter, error) {
+	var (
+		tables  []string
+		clauses = make(map[string][]string)
+	)
+	for _, stmt := range stmts {
+		m := alterTableStmtRegex.FindStringSubmatch(stmt)
+		if m == nil {
+			continue
+		}
+		table := unquoteIdentifier(m[1])
+		if _, ok := clauses[table]; !ok {
+			tables = append(tables, table)
+		}
+		clauses[table] = append(clauses[table], m[2])
+	}
+	if len(tables) == 0 {
+		return nil, nil
+	}
+
+	var sizes map[string]int64
+	if osc.MinTableSizeMB > 0 && slices.ContainsFunc(tables, func(t string) bool { return !slices.Contains(osc.Tables, t) }) {
+		var err error
+		if sizes, err = p.OnlineSchemaChange.TableSizes(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	var alters []onlineAlter
+	for _, t := range tables {
+		if !slices.Contains(osc.Tables, t) && (osc.MinTableSizeMB <= 0 || sizes[t] < osc.MinTableSizeMB<<20) {
+			continue
+		}
+		alters = append(alters, onlineAlter{Table: t, Alter: strings.Join(clauses[t], ", ")})
+	}
+
+	for _, stmt := range stmts {
+		table, ok := changedTable(stmt)
+		if ok && slices.ContainsFunc(alters, func(a onlineAlter) bool { return a.Table == table }) {
+			return nil, fmt.Errorf("table %s is altered online with gh-ost but also changed by %q, deploy the change separately", table, stmt)
+		}
+	}
+	return alters, nil
+}
+
+// changedTable returns the table changed by the given statement other than ALTER TABLE.
+func changedTable(stmt string) (string, bool) {
+	for _, r := range tableStmtRegexes {
+		if m := r.FindStringSubmatch(stmt); m != nil {
+			return unquoteIdentifier(m[len(m)-1]), true
+		}
+	}
+	return "", false
+}
+
+// skipOnlineAlters returns the target for sqldef which does not touch the tables altered with gh-ost.
+func skipOnlineAlters(target provider.Target, alters []onlineAlter) provider.Target {
+	if len(alters) == 0 {
+		return target
+	}
+	target.SkipTables = slices.Clone(target.SkipTables)
+	for _, a := range alters {
+		target.SkipTables = append(target.SkipTables, regexp.QuoteMeta(a.Table))
+	}
+	return target
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+)
+
+type MockOnlineSchemaChangeProvider struct {
+	mock.Mock
+}
+
+func (m *MockOnlineSchemaChangeProvider) TableSizes(ctx context.Context, cfg config.DeployTargetConfig) (map[string]int64, error) {
+	args := m.Called(ctx, cfg)
+	sizes, _ := args.Get(0).(map[string]int64)
+	return sizes, args.Error(1)
+}
+
+func (m *MockOnlineSchemaChangeProvider) Alter(ctx context.Context, logger sdk.StageLogPersister, target provider.Target, table, alter string, extraArgs []string) error {
+	args := m.Called(ctx, target.Config, table, alter, extraArgs)
+	return args.Error(0)
+}
+
+func TestPlugin_planOnlineAlters(t *testing.T) {
+	t.Parallel()
+
+	targetConfig := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "testdb"}
+	stmts := []string{
+		"ALTER TABLE `users` ADD COLUMN `age` int NOT NULL DEFAULT 0;",
+		"ALTER TABLE `posts` ADD COLUMN `title` varchar(255);",
+		"CREATE INDEX idx_comments_body ON comments (body);",
+		"ALTER TABLE `users` ADD INDEX `idx_age` (`age`);",
+		"ALTER TABLE `tags` DROP COLUMN `color`;",
+	}
+
+	testcases := []struct {
+		name     string
+		osc      config.OnlineSchemaChangeConfig
+		sizes    map[string]int64
+		expected []onlineAlter
+	}{
+		{
+			name: "configured tables",
+			osc:  config.OnlineSchemaChangeConfig{Tables: []string{"users"}},
+			expected: []onlineAlter{
+				{Table: "users", Alter: "ADD COLUMN `age` int NOT NULL DEFAULT 0, ADD INDEX `idx_age` (`age`)"},
+			},
+		},
+		{
+			name:  "tables over the size threshold",
+			osc:   config.OnlineSchemaChangeConfig{Tables: []string{"tags"}, MinTableSizeMB: 100},
+			sizes: map[string]int64{"users": 50 << 20, "posts": 100 << 20, "tags": 1 << 20},
+			expected: []onlineAlter{
+				{Table: "posts", Alter: "ADD COLUMN `title` varchar(255)"},
+				{Table: "tags", Alter: "DROP COLUMN `color`"},
+			},
+		},
+		{
+			name: "no matching tables",
+			osc:  config.OnlineSchemaChangeConfig{Tables: []string{"comments"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockOSC := &MockOnlineSchemaChangeProvider{}
+			if tc.sizes != nil {
+				mockOSC.On("TableSizes", ctx, targetConfig).Return(tc.sizes, nil).Once()
+			}
+
+			plugin := &Plugin{OnlineSchemaChange: mockOSC}
+			alters, err := plugin.planOnlineAlters(ctx, targetConfig, tc.osc, stmts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, alters)
+			mockOSC.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPlugin_planOnlineAlters_OtherChanges(t *testing.T) {
+	t.Parallel()
+
+	osc := config.OnlineSchemaChangeConfig{Tables: []string{"users"}}
+	testcases := []struct {
+		name    string
+		stmt    string
+		wantErr bool
+	}{
+		{name: "index on the table", stmt: "CREATE INDEX idx_users_age ON `users` (`age`);", wantErr: true},
+		{name: "trigger on the table", stmt: "CREATE TRIGGER `users_audit` AFTER UPDATE ON `users` FOR EACH ROW SET @n = 1;", wantErr: true},
+		{name: "index on another table", stmt: "CREATE INDEX idx_posts_title ON `posts` (`title`);"},
+		{name: "new table", stmt: "CREATE TABLE `comments` (\n  `id` int\n);"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			plugin := &Plugin{OnlineSchemaChange: &MockOnlineSchemaChangeProvider{}}
+			_, err := plugin.planOnlineAlters(context.Background(), config.DeployTargetConfig{DbType: config.DBTypeMySQL}, osc, []string{
+				"ALTER TABLE `users` ADD COLUMN `age` int;",
+				tc.stmt,
+			})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSkipOnlineAlters(t *testing.T) {
+	t.Parallel()
+
+	target := provider.Target{SkipTables: []string{"(.*\\.)?sqldef_migrations"}}
+	got := skipOnlineAlters(target, []onlineAlter{{Table: "user.logs"}})
+
+	assert.Equal(t, []string{"(.*\\.)?sqldef_migrations", "user\\.logs"}, got.SkipTables)
+	assert.Equal(t, []string{"(.*\\.)?sqldef_migrations"}, target.SkipTables)
+}
+
+func TestPlugin_executeApplyStage_OnlineSchemaChange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	schemaFiles := []string{filepath.Join("testdata", "app", "schema.sql")}
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
+	args := []string{"--max-load=Threads_running=25"}
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, targetConfig, schemaFiles, true).Return("ALTER TABLE `users` ADD COLUMN `age` int;\nCREATE TABLE `posts` (\n  `id` int\n);\n", nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig, schemaFiles, false).Return("", nil)
+
+	mockOSC := &MockOnlineSchemaChangeProvider{}
+	mockOSC.On("Alter", ctx, targetConfig, "users", "ADD COLUMN `age` int", args).Return(nil).Once()
+
+	ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+		ApplicationDirectory: filepath.Join("testdata", "app"),
+		CommitHash:           "0123456789",
+		ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+			Spec: &config.ApplicationConfigSpec{
+				OnlineSchemaChange: config.OnlineSchemaChangeConfig{Tables: []string{"users"}, Args: args},
+			},
+		},
+	}
+	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:               sqldefStageApply,
+			RunningDeploymentSource: ds,
+			TargetDeploymentSource:  ds,
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+	}
+
+	plugin := &Plugin{Sqldef: mockSqldef, OnlineSchemaChange: mockOSC}
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "test-mysql", Config: targetConfig},
+	}, input)
+
+	assert.Equal(t, sdk.StageStatusSuccess, status)
+	mockSqldef.AssertExpectations(t)
+	mockOSC.AssertExpectations(t)
+}
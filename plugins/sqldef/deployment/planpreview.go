@@ -0,0 +1,142 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
+)
+
+var _ sdk.PlanPreviewPlugin[config.Config, config.DeployTargetConfig, config.ApplicationConfigSpec] = (*Plugin)(nil)
+
+// GetPlanPreview returns the SQL statements which sqldef would execute against each deploy target.
+func (p *Plugin) GetPlanPreview(
+	ctx context.Context,
+	cfg *config.Config,
+	dts []*sdk.DeployTarget[config.DeployTargetConfig],
+	input *sdk.GetPlanPreviewInput[config.ApplicationConfigSpec],
+) (*sdk.GetPlanPreviewResponse, error) {
+	toolRegistry := toolRegistryPkg.NewRegistry(input.Client.ToolRegistry())
+	lp := zapLogPersister{logger: input.Logger}
+
+	ds := input.Request.TargetDeploymentSource
+	schemaFiles, err := resolveSchemaFiles(ds.ApplicationDirectory, applicationSpec(ds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find schema files: %w", err)
+	}
+
+	results := make([]sdk.PlanPreviewResult, 0, len(dts))
+	for _, dt := range dts {
+		if err := dt.Config.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config of deploy target %s: %w", dt.Name, err)
+		}
+
+		execPath, err := toolRegistry.DownloadBinary(ctx, dt.Config.DbType, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sqldef tool for deploy target %s: %w", dt.Name, err)
+		}
+
+		result, err := p.Sqldef.Execute(ctx, lp, sqldefTarget(dt.Config, execPath, applicationSpec(ds)), schemaFiles, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan deploy target %s: %w", dt.Name, err)
+		}
+
+		results = append(results, planPreviewResult(dt.Name, result))
+	}
+
+	return &sdk.GetPlanPreviewResponse{
+		Results: results,
+	}, nil
+}
+
+// planPreviewResult builds the plan preview result from the result of the sqldef dry run.
+// Destructive statements are marked with a comment line just before them.
+func planPreviewResult(target string, result provider.ExecuteResult) sdk.PlanPreviewResult {
+	if result.NoChanges() {
+		return sdk.PlanPreviewResult{
+			DeployTarget: target,
+			Summary:      "No changes",
+			NoChange:     true,
+		}
+	}
+
+	var (
+		details     strings.Builder
+		destructive int
+	)
+	for _, stmt := range result.Statements {
+		if kind := classifyStatement(stmt); kind != "" {
+			destructive++
+			fmt.Fprintf(&details, "-- DESTRUCTIVE: %s\n", kind)
+		}
+		details.WriteString(stmt)
+		details.WriteString("\n")
+	}
+
+	summary := fmt.Sprintf("%d statement(s) to execute", len(result.Statements))
+	if destructive > 0 {
+		summary = fmt.Sprintf("%s, including %d destructive", summary, destructive)
+	}
+
+	return sdk.PlanPreviewResult{
+		DeployTarget: target,
+		Summary:      summary,
+		Details:      []byte(details.String()),
+		DiffLanguage: "sql",
+	}
+}
+
+// zapLogPersister writes the logs of the provider to the zap logger
+// since there is no stage log while building the plan preview.
+type zapLogPersister struct {
+	logger *zap.Logger
+}
+
+func (l zapLogPersister) Write(log []byte) (int, error) {
+	l.logger.Debug(string(log))
+	return len(log), nil
+}
+
+func (l zapLogPersister) Info(log string) {
+	l.logger.Debug(log)
+}
+
+func (l zapLogPersister) Infof(format string, a ...any) {
+	l.logger.Debug(fmt.Sprintf(format, a...))
+}
+
+func (l zapLogPersister) Success(log string) {
+	l.logger.Debug(log)
+}
+
+func (l zapLogPersister) Successf(format string, a ...any) {
+	l.logger.Debug(fmt.Sprintf(format, a...))
+}
+
+func (l zapLogPersister) Error(log string) {
+	l.logger.Error(log)
+}
+
+func (l zapLogPersister) Errorf(format string, a ...any) {
+	l.logger.Error(fmt.Sprintf(format, a...))
+}
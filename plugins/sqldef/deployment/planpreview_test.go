@@ -0,0 +1,161 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+)
+
+func TestPlanPreviewResult(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		output   string
+		expected sdk.PlanPreviewResult
+	}{
+		{
+			name:   "no changes",
+			output: "-- Nothing is modified --\n",
+			expected: sdk.PlanPreviewResult{
+				DeployTarget: "db",
+				Summary:      "No changes",
+				NoChange:     true,
+			},
+		},
+		{
+			name:   "safe changes",
+			output: "-- dry run --\nBEGIN;\nCREATE TABLE users (id INT);\nCOMMIT;\n",
+			expected: sdk.PlanPreviewResult{
+				DeployTarget: "db",
+				Summary:      "1 statement(s) to execute",
+				Details:      []byte("CREATE TABLE users (id INT);\n"),
+				DiffLanguage: "sql",
+			},
+		},
+		{
+			name:   "destructive changes are marked",
+			output: "-- dry run --\nALTER TABLE users ADD COLUMN age INT;\nDROP TABLE logs;\n",
+			expected: sdk.PlanPreviewResult{
+				DeployTarget: "db",
+				Summary:      "2 statement(s) to execute, including 1 destructive",
+				Details:      []byte("ALTER TABLE users ADD COLUMN age INT;\n-- DESTRUCTIVE: DROP TABLE\nDROP TABLE logs;\n"),
+				DiffLanguage: "sql",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, planPreviewResult("db", provider.NewExecuteResult(tt.output)))
+		})
+	}
+}
+
+func TestPlugin_GetPlanPreview(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	schemaFiles := []string{filepath.Join("testdata", "app", "schema.sql")}
+
+	mysqlConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
+	sqliteConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/test.db",
+	}
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, mysqlConfig, schemaFiles, true).Return("-- Nothing is modified --\n", nil)
+	mockSqldef.On("Execute", ctx, sqliteConfig, schemaFiles, true).Return("DROP TABLE logs;\n", nil)
+
+	input := &sdk.GetPlanPreviewInput[config.ApplicationConfigSpec]{
+		Request: sdk.GetPlanPreviewRequest[config.ApplicationConfigSpec]{
+			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: filepath.Join("testdata", "app"),
+				CommitHash:           "0123456789",
+			},
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+		Logger: zap.NewNop(),
+	}
+	dts := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "mysql", Config: mysqlConfig},
+		{Name: "sqlite", Config: sqliteConfig},
+	}
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	resp, err := plugin.GetPlanPreview(ctx, nil, dts, input)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "mysql", resp.Results[0].DeployTarget)
+	assert.True(t, resp.Results[0].NoChange)
+	assert.Equal(t, "sqlite", resp.Results[1].DeployTarget)
+	assert.False(t, resp.Results[1].NoChange)
+	assert.Equal(t, "1 statement(s) to execute, including 1 destructive", resp.Results[1].Summary)
+	mockSqldef.AssertExpectations(t)
+}
+
+func TestPlugin_GetPlanPreview_Error(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	targetConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/test.db",
+	}
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return("", errors.New("connection refused"))
+
+	input := &sdk.GetPlanPreviewInput[config.ApplicationConfigSpec]{
+		Request: sdk.GetPlanPreviewRequest[config.ApplicationConfigSpec]{
+			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: filepath.Join("testdata", "app"),
+			},
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+		Logger: zap.NewNop(),
+	}
+	dts := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "sqlite", Config: targetConfig},
+	}
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	_, err := plugin.GetPlanPreview(ctx, nil, dts, input)
+	assert.ErrorContains(t, err, "failed to plan deploy target sqlite: connection refused")
+}
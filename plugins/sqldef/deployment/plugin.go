@@ -17,11 +17,19 @@ const (
 	sqldefStageApply string = "SQLDEF_APPLY"
 	// revert changes to target DB
 	sqldefStageRollback string = "SQLDEF_ROLLBACK"
+	// run the versioned migration scripts which have not been applied yet
+	sqldefStageMigrate string = "SQLDEF_MIGRATE"
+	// check the schema files against the lint rules without connecting to target DB
+	sqldefStageLint string = "SQLDEF_LINT"
+	// export the current schema of a target DB to bootstrap the schema file
+	sqldefStageExport string = "SQLDEF_EXPORT"
 )
 
 // Plugin implements sdk.DeploymentPlugin for Sqldef.
 type Plugin struct {
-	Sqldef provider.SqldefProvider
+	Sqldef             provider.SqldefProvider
+	Migrator           provider.MigrationProvider
+	OnlineSchemaChange provider.OnlineSchemaChangeProvider
 }
 
 var _ sdk.DeploymentPlugin[config.Config, config.DeployTargetConfig, config.ApplicationConfigSpec] = (*Plugin)(nil)
@@ -31,6 +39,9 @@ func (*Plugin) FetchDefinedStages() []string {
 		sqldefStagePlan,
 		sqldefStageApply,
 		sqldefStageRollback,
+		sqldefStageMigrate,
+		sqldefStageLint,
+		sqldefStageExport,
 	}
 }
 
@@ -49,7 +60,7 @@ func (p *Plugin) BuildPipelineSyncStages(
 			Name:               s.Name,
 			Rollback:           false,
 			Metadata:           make(map[string]string),
-			AvailableOperation: sdk.ManualOperationNone,
+			AvailableOperation: availableOperation(s.Name),
 		})
 	}
 
@@ -70,6 +81,17 @@ func (p *Plugin) BuildPipelineSyncStages(
 	}, nil
 }
 
+// availableOperation returns the manual operation available for the given stage.
+// The apply stage can be approved since it waits for an approval to apply destructive changes
+// when destructiveChanges is "approval". The application config is not given when building the stages,
+// so the operation is available under the other policies too, where approving the stage does nothing.
+func availableOperation(stage string) sdk.ManualOperation {
+	if stage == sqldefStageApply {
+		return sdk.ManualOperationApprove
+	}
+	return sdk.ManualOperationNone
+}
+
 // ExecuteStage executes the given stage.
 func (p *Plugin) ExecuteStage(
 	ctx context.Context,
@@ -85,11 +107,23 @@ func (p *Plugin) ExecuteStage(
 		}, nil
 	case sqldefStageApply:
 		return &sdk.ExecuteStageResponse{
-			Status: p.executeApplyStage(ctx, dts, input),
+			Status: p.executeApplyStage(ctx, input.Client, dts, input),
 		}, nil
 	case sqldefStageRollback:
 		return &sdk.ExecuteStageResponse{
-			Status: p.executeRollbackStage(ctx, dts, input),
+			Status: p.executeRollbackStage(ctx, input.Client, dts, input),
+		}, nil
+	case sqldefStageMigrate:
+		return &sdk.ExecuteStageResponse{
+			Status: p.executeMigrateStage(ctx, dts, input),
+		}, nil
+	case sqldefStageLint:
+		return &sdk.ExecuteStageResponse{
+			Status: p.executeLintStage(ctx, input),
+		}, nil
+	case sqldefStageExport:
+		return &sdk.ExecuteStageResponse{
+			Status: p.executeExportStage(ctx, input.Client, dts, input),
 		}, nil
 	default:
 		panic("unimplemented stage")
@@ -132,7 +166,7 @@ func (p *Plugin) BuildQuickSyncStages(
 		Description:        "Apply changes to target DB",
 		Rollback:           false,
 		Metadata:           map[string]string{},
-		AvailableOperation: sdk.ManualOperationNone,
+		AvailableOperation: availableOperation(sqldefStageApply),
 	})
 
 	if input.Request.Rollback {
@@ -41,7 +41,7 @@ func Test_BuildPipelineSyncStages(t *testing.T) {
 			expected: &sdk.BuildPipelineSyncStagesResponse{
 				Stages: []sdk.PipelineStage{
 					{Name: sqldefStagePlan, Index: 1, Rollback: false, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationNone},
-					{Name: sqldefStageApply, Index: 2, Rollback: false, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationNone},
+					{Name: sqldefStageApply, Index: 2, Rollback: false, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationApprove},
 				},
 			},
 		},
@@ -59,7 +59,7 @@ func Test_BuildPipelineSyncStages(t *testing.T) {
 			expected: &sdk.BuildPipelineSyncStagesResponse{
 				Stages: []sdk.PipelineStage{
 					{Name: sqldefStagePlan, Index: 4, Rollback: false, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationNone},
-					{Name: sqldefStageApply, Index: 3, Rollback: false, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationNone},
+					{Name: sqldefStageApply, Index: 3, Rollback: false, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationApprove},
 					{Name: sqldefStageRollback, Index: 3, Rollback: true, Metadata: map[string]string{}, AvailableOperation: sdk.ManualOperationNone},
 				},
 			},
@@ -101,7 +101,7 @@ func TestPlugin_BuildQuickSyncStages(t *testing.T) {
 						Description:        "Apply changes to target DB",
 						Rollback:           false,
 						Metadata:           map[string]string{},
-						AvailableOperation: sdk.ManualOperationNone,
+						AvailableOperation: sdk.ManualOperationApprove,
 					},
 				},
 			},
@@ -120,7 +120,7 @@ func TestPlugin_BuildQuickSyncStages(t *testing.T) {
 						Description:        "Apply changes to target DB",
 						Rollback:           false,
 						Metadata:           map[string]string{},
-						AvailableOperation: sdk.ManualOperationNone,
+						AvailableOperation: sdk.ManualOperationApprove,
 					},
 					{
 						Name:               sqldefStageRollback,
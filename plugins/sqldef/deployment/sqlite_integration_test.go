@@ -0,0 +1,127 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
+)
+
+// newSQLiteStageInput creates the stage input deploying the schema in targetDir
+// while the schema in runningDir is the currently deployed one.
+func newSQLiteStageInput(t *testing.T, client *sdk.Client, stageName, runningDir, targetDir string) *sdk.ExecuteStageInput[config.ApplicationConfigSpec] {
+	t.Helper()
+	return &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:   stageName,
+			StageConfig: []byte(``),
+			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: runningDir,
+				CommitHash:           "0123456789",
+			},
+			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: targetDir,
+				CommitHash:           "9876543210",
+			},
+			Deployment: sdk.Deployment{
+				PipedID:       "piped-id",
+				ApplicationID: "app-id",
+			},
+		},
+		Client: client,
+	}
+}
+
+// integrationTestEnv enables the integration tests which download sqldef binaries from GitHub.
+const integrationTestEnv = "SQLDEF_INTEGRATION_TEST"
+
+func TestPlugin_SQLiteIntegration(t *testing.T) {
+	if os.Getenv(integrationTestEnv) == "" {
+		t.Skipf("skipping integration test which downloads sqlite3def, set %s=1 to run it", integrationTestEnv)
+	}
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRegistry := toolregistrytest.NewTestToolRegistry(t)
+	client := sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), testRegistry)
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	dts := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{
+			Name: "test-sqlite",
+			Config: config.DeployTargetConfig{
+				DbType: config.DBTypeSQLite,
+				Path:   dbPath,
+			},
+		},
+	}
+
+	v1 := filepath.Join("testdata", "sqlite", "v1")
+	v2 := filepath.Join("testdata", "sqlite", "v2")
+
+	plugin := &Plugin{Sqldef: &provider.SqldefProviderImpl{}}
+	// The metadata store of the deployment of the second version.
+	store := newFakeMetadataStore()
+
+	// currentSchema exports the schema of the database with a separated provider.
+	currentSchema := func(t *testing.T) string {
+		t.Helper()
+		execPath, err := toolRegistryPkg.NewRegistry(testRegistry).DownloadBinary(ctx, config.DBTypeSQLite, "")
+		require.NoError(t, err)
+
+		p := &provider.SqldefProviderImpl{}
+		schema, err := p.ShowCurrentSchema(ctx, provider.Target{Config: dts[0].Config, ExecPath: execPath})
+		require.NoError(t, err)
+		return schema
+	}
+
+	// Deploy the first version.
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), dts, newSQLiteStageInput(t, client, sqldefStageApply, v1, v1))
+	require.Equal(t, sdk.StageStatusSuccess, status)
+	schema := currentSchema(t)
+	assert.Contains(t, schema, "users")
+	assert.NotContains(t, schema, "email")
+
+	// Planning the second version must not change the database.
+	status = plugin.executePlanStage(ctx, dts, newSQLiteStageInput(t, client, sqldefStagePlan, v1, v2))
+	require.Equal(t, sdk.StageStatusSuccess, status)
+	assert.NotContains(t, currentSchema(t), "email")
+
+	// Apply the second version.
+	status = plugin.executeApplyStage(ctx, store, dts, newSQLiteStageInput(t, client, sqldefStageApply, v1, v2))
+	require.Equal(t, sdk.StageStatusSuccess, status)
+	assert.Contains(t, currentSchema(t), "email")
+
+	// Rollback to the schema before the deployment of the second version.
+	status = plugin.executeRollbackStage(ctx, store, dts, newSQLiteStageInput(t, client, sqldefStageRollback, v1, v2))
+	require.Equal(t, sdk.StageStatusSuccess, status)
+	schema = currentSchema(t)
+	assert.Contains(t, schema, "users")
+	assert.NotContains(t, schema, "email")
+}
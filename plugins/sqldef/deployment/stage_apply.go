@@ -16,47 +16,197 @@ package deployment
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
 	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
 	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 )
 
-func (p *Plugin) executeApplyStage(ctx context.Context, dts []*sdk.DeployTarget[config.DeployTargetConfig], input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
+// applyTarget is a deploy target checked by the dry run before applying.
+type applyTarget struct {
+	name        string
+	target      provider.Target
+	schemaFiles []string
+	// statements are the changes found by the dry run.
+	statements []string
+	// approved is true when the statements were approved manually.
+	// They are checked again right before applying since the database may be changed while waiting for the approval.
+	approved bool
+	// onlineAlters are run with gh-ost after applying the other changes with sqldef.
+	onlineAlters []onlineAlter
+}
+
+func (p *Plugin) executeApplyStage(ctx context.Context, store metadataStore, dts []*sdk.DeployTarget[config.DeployTargetConfig], input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
 	lp := input.Client.LogPersister()
 	lp.Info("Start applying the schema deployment")
 
 	// Currently, we create them every time the stage is executed because we can't pass input.Client.toolRegistry to the plugin when starting the plugin.
 	toolRegistry := toolRegistryPkg.NewRegistry(input.Client.ToolRegistry())
 
-	for _, dt := range dts {
-		lp.Infof("Deploy Target [%s]: host=%s, port=%s, db=%s",
-			dt.Name,
-			dt.Config.Host,
-			dt.Config.Port,
-			dt.Config.DBName,
-		)
+	spec := applicationSpec(input.Request.TargetDeploymentSource)
+	exec := executionConfig(spec)
+
+	// Check the changes of all targets by the dry run first
+	// so that destructive changes are handled once before applying to any of them.
+	// Targets without changes are not applied.
+	var (
+		mu          sync.Mutex
+		destructive int
+		targets     = make([]*applyTarget, len(dts))
+	)
+	results := runTargets(lp, exec, targetNames(dts), func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		dt := dts[i]
+		lp.Infof("Deploy Target [%s]: %s", dt.Name, describeTarget(dt.Config))
+
+		if err := dt.Config.Validate(); err != nil {
+			return "", fmt.Errorf("invalid deploy target config: %w", err)
+		}
 
 		sqlDefPath, err := toolRegistry.DownloadBinary(ctx, dt.Config.DbType, "")
 		if err != nil {
-			lp.Errorf("Failed while getting Sqldef tool (%v)", err)
-			return sdk.StageStatusFailure
+			return "", fmt.Errorf("failed while getting Sqldef tool: %w", err)
+		}
+
+		schemaFiles, err := resolveSchemaFiles(input.Request.TargetDeploymentSource.ApplicationDirectory, spec)
+		if err != nil {
+			return "", fmt.Errorf("failed while finding schema files: %w", err)
+		}
+
+		target := sqldefTarget(dt.Config, sqlDefPath, spec)
+
+		result, err := p.Sqldef.Execute(ctx, lp, target, schemaFiles, true)
+		if err != nil {
+			return "", fmt.Errorf("failed while checking the changes: %w", err)
+		}
+		reportChanges(lp, dt.Name, result)
+		if result.NoChanges() {
+			return targetStatusNoChange, nil
 		}
 
-		appDir := input.Request.TargetDeploymentSource.ApplicationDirectory
-		schemaPath, err := findFirstSQLFile(appDir)
+		changes := classifyDestructiveChanges(result.Statements)
+		reportDestructiveChanges(lp, dt.Name, changes)
+
+		alters, err := p.onlineAlters(ctx, lp, dt, spec, result.Statements)
 		if err != nil {
-			lp.Errorf("Failed while finding schema file (%v)", err)
-			return sdk.StageStatusFailure
+			return "", fmt.Errorf("failed while checking the tables to alter online: %w", err)
 		}
 
-		p.Sqldef.Init(lp, dt.Config.Username, dt.Config.Password, dt.Config.Host, dt.Config.Port, dt.Config.DBName, schemaPath, sqlDefPath)
+		mu.Lock()
+		destructive += len(changes)
+		mu.Unlock()
+		targets[i] = &applyTarget{name: dt.Name, target: target, schemaFiles: schemaFiles, statements: result.Statements, onlineAlters: alters}
+		return targetStatusSuccess, nil
+	})
+
+	// Apply to the targets checked successfully. Unless continuing on error, nothing is applied after a failure.
+	var pending []int
+	for i, r := range results {
+		if r.Status != targetStatusSuccess {
+			continue
+		}
+		// They are reported as skipped unless they are applied.
+		results[i].Status = targetStatusSkipped
+		pending = append(pending, i)
+	}
+	if slices.ContainsFunc(results, func(r targetResult) bool { return r.Status == targetStatusFailed }) && !exec.ContinueOnError() {
+		reportTargetResults(lp, results)
+		return sdk.StageStatusFailure
+	}
 
-		if err := p.Sqldef.Execute(ctx, false); err != nil {
-			lp.Errorf("Failed while applying the deployment (%v)", err)
-			return sdk.StageStatusFailure
+	policy := destructivePolicy(spec)
+	if policy != config.DestructiveChangePolicyApproval {
+		lp.Infof("Approving this stage has no effect since destructiveChanges is %q", policy)
+	}
+	if err := enforceDestructivePolicy(ctx, input.Client, lp, policy, destructive); err != nil {
+		lp.Errorf("Failed while checking destructive changes (%v)", err)
+		reportTargetResults(lp, results)
+		return sdk.StageStatusFailure
+	}
+	if policy == config.DestructiveChangePolicyApproval && destructive > 0 {
+		for _, i := range pending {
+			targets[i].approved = true
 		}
 	}
 
+	names := make([]string, 0, len(pending))
+	for _, i := range pending {
+		names = append(names, dts[i].Name)
+	}
+	applied := runTargets(lp, exec, names, func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		return targetStatusSuccess, p.applyToTarget(ctx, store, lp, toolRegistry, spec, targets[pending[i]])
+	})
+	for i, r := range applied {
+		results[pending[i]] = r
+	}
+
+	if !reportTargetResults(lp, results) {
+		return sdk.StageStatusFailure
+	}
 	return sdk.StageStatusSuccess
 }
+
+// applyToTarget applies the changes checked by the dry run to the deploy target.
+func (p *Plugin) applyToTarget(ctx context.Context, store metadataStore, lp sdk.StageLogPersister, toolRegistry *toolRegistryPkg.Registry, spec *config.ApplicationConfigSpec, t *applyTarget) error {
+	lp.Infof("Applying to Deploy Target [%s]", t.name)
+
+	if t.approved {
+		result, err := p.Sqldef.Execute(ctx, lp, t.target, t.schemaFiles, true)
+		if err != nil {
+			return fmt.Errorf("failed while checking the changes: %w", err)
+		}
+		if !slices.Equal(result.Statements, t.statements) {
+			return errors.New("the changes differ from the approved ones, the database may have been changed while waiting for the approval")
+		}
+	}
+
+	if err := exportPreApplySchema(ctx, store, p.Sqldef, t.name, t.target); err != nil {
+		return fmt.Errorf("failed while exporting the current schema: %w", err)
+	}
+
+	if _, err := p.Sqldef.Execute(ctx, lp, skipOnlineAlters(t.target, t.onlineAlters), t.schemaFiles, false); err != nil {
+		return fmt.Errorf("failed while applying the deployment: %w", err)
+	}
+
+	if len(t.onlineAlters) == 0 {
+		return nil
+	}
+
+	ghostPath, err := toolRegistry.DownloadGhost(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed while getting gh-ost tool: %w", err)
+	}
+
+	// gh-ost runs for hours on large tables, so its progress is streamed instead of waiting for the deploy target to finish.
+	out := streamLogPersister(lp)
+	defer out.flush()
+	for _, a := range t.onlineAlters {
+		out.Infof("Altering table %s online with gh-ost", a.Table)
+		if err := p.OnlineSchemaChange.Alter(ctx, out, provider.Target{Config: t.target.Config, ExecPath: ghostPath}, a.Table, a.Alter, spec.OnlineSchemaChange.Args); err != nil {
+			return fmt.Errorf("failed while altering table %s online: %w", a.Table, err)
+		}
+	}
+	return nil
+}
+
+// exportPreApplySchema saves the current schema of the deploy target so that the rollback stage can restore it.
+// The schema exported by a previous stage of the same deployment is kept as it is the one before the deployment.
+func exportPreApplySchema(ctx context.Context, store metadataStore, sqldef provider.SqldefProvider, name string, target provider.Target) error {
+	key := preApplySchemaKey(name)
+	if _, found, err := store.GetDeploymentPluginMetadata(ctx, key); err != nil {
+		return err
+	} else if found {
+		return nil
+	}
+
+	schema, err := sqldef.ShowCurrentSchema(ctx, target)
+	if err != nil {
+		return err
+	}
+	return store.PutDeploymentPluginMetadata(ctx, key, schema)
+}
@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
@@ -27,15 +26,19 @@ func TestPlugin_executeApplyStage_HappyPath(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser", "testpass", "localhost", "3306", "testdb",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return()
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
 
 	// Key difference from plan stage: dryRun=false for apply stage
-	mockSqldef.On("Execute", ctx, false).Return(nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil)
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -43,11 +46,11 @@ func TestPlugin_executeApplyStage_HappyPath(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -77,7 +80,85 @@ func TestPlugin_executeApplyStage_HappyPath(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
+
+	// Assert success
+	assert.Equal(t, sdk.StageStatusSuccess, status)
+
+	// Verify all mock expectations were met
+	mockSqldef.AssertExpectations(t)
+}
+
+func TestPlugin_executeApplyStage_Postgres(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Initialize tool registry
+	testRegistry := toolregistrytest.NewTestToolRegistry(t)
+
+	// Create mock sqldef provider
+	mockSqldef := &MockSqldefProvider{}
+
+	// Setup expectations for the mock
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypePostgres,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "5432",
+		DBName:   "testdb",
+		Schema:   "app",
+		SSLMode:  "require",
+	}
+
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil)
+
+	// Prepare the input
+	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:   sqldefStageApply,
+			StageConfig: []byte(``),
+			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: filepath.Join("testdata", "app"),
+				CommitHash:           "0123456789",
+			},
+			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
+				ApplicationDirectory: filepath.Join("testdata", "app"),
+				CommitHash:           "0123456789",
+			},
+			Deployment: sdk.Deployment{
+				PipedID:       "piped-id",
+				ApplicationID: "app-id",
+			},
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), testRegistry),
+	}
+
+	// Create deploy targets
+	deployTargets := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{
+			Name: "test-postgres",
+			Config: config.DeployTargetConfig{
+				DbType:   config.DBTypePostgres,
+				Username: "testuser",
+				Password: "testpass",
+				Host:     "localhost",
+				Port:     "5432",
+				DBName:   "testdb",
+				Schema:   "app",
+				SSLMode:  "require",
+			},
+		},
+	}
+
+	// Create plugin with mock sqldef provider
+	plugin := createPluginWithMockSqldef(mockSqldef)
+
+	// Execute the apply stage
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert success
 	assert.Equal(t, sdk.StageStatusSuccess, status)
@@ -98,25 +179,33 @@ func TestPlugin_executeApplyStage_MultipleTargets(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock - should be called twice for two targets
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser1", "testpass1", "localhost", "3306", "testdb1",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
+	targetConfig1 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser1",
+		Password: "testpass1",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb1",
+	}
 
 	// Key difference: dryRun=false for apply stage
-	mockSqldef.On("Execute", ctx, false).Return(nil).Once()
-
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser2", "testpass2", "localhost", "3307", "testdb2",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig1).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil).Once()
+
+	targetConfig2 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser2",
+		Password: "testpass2",
+		Host:     "localhost",
+		Port:     "3307",
+		DBName:   "testdb2",
+	}
 
 	// Key difference: dryRun=false for apply stage
-	mockSqldef.On("Execute", ctx, false).Return(nil).Once()
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig2).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil).Once()
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -124,11 +213,11 @@ func TestPlugin_executeApplyStage_MultipleTargets(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -169,7 +258,7 @@ func TestPlugin_executeApplyStage_MultipleTargets(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert success - should handle multiple targets successfully
 	assert.Equal(t, sdk.StageStatusSuccess, status)
@@ -195,11 +284,11 @@ func TestPlugin_executeApplyStage_EmptyDeployTargets(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -217,7 +306,7 @@ func TestPlugin_executeApplyStage_EmptyDeployTargets(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert success - empty targets should be handled gracefully
 	assert.Equal(t, sdk.StageStatusSuccess, status)
@@ -243,11 +332,11 @@ func TestPlugin_executeApplyStage_UnsupportedDBType(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -263,7 +352,7 @@ func TestPlugin_executeApplyStage_UnsupportedDBType(t *testing.T) {
 		{
 			Name: "test-postgres",
 			Config: config.DeployTargetConfig{
-				DbType:   config.DBTypePostgres, // Unsupported type
+				DbType:   config.DBType("oracle"), // Unsupported type
 				Username: "testuser",
 				Password: "testpass",
 				Host:     "localhost",
@@ -277,7 +366,7 @@ func TestPlugin_executeApplyStage_UnsupportedDBType(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert failure
 	assert.Equal(t, sdk.StageStatusFailure, status)
@@ -337,7 +426,7 @@ func TestPlugin_executeApplyStage_SchemaFileNotFound(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert failure
 	assert.Equal(t, sdk.StageStatusFailure, status)
@@ -358,15 +447,19 @@ func TestPlugin_executeApplyStage_SqldefExecuteError(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser", "testpass", "localhost", "3306", "testdb",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return()
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
 
 	// Mock Execute to return an error with dryRun=false
-	mockSqldef.On("Execute", ctx, false).Return(assert.AnError)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", assert.AnError)
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -374,11 +467,11 @@ func TestPlugin_executeApplyStage_SqldefExecuteError(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -408,7 +501,7 @@ func TestPlugin_executeApplyStage_SqldefExecuteError(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert failure - apply stage should fail when sqldef.Execute fails
 	assert.Equal(t, sdk.StageStatusFailure, status)
@@ -431,14 +524,18 @@ func TestPlugin_executeApplyStage_BinaryDownloadFailure(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock since binary download will succeed
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser", "testpass", "localhost", "3306", "testdb",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return()
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
 
-	mockSqldef.On("Execute", ctx, false).Return(nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil)
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -446,11 +543,11 @@ func TestPlugin_executeApplyStage_BinaryDownloadFailure(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -480,7 +577,7 @@ func TestPlugin_executeApplyStage_BinaryDownloadFailure(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert success - test registry provides mock binaries successfully
 	assert.Equal(t, sdk.StageStatusSuccess, status)
@@ -501,24 +598,32 @@ func TestPlugin_executeApplyStage_MultipleTargetsWithFailure(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the first target (success)
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser1", "testpass1", "localhost", "3306", "testdb1",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
+	targetConfig1 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser1",
+		Password: "testpass1",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb1",
+	}
 
-	mockSqldef.On("Execute", ctx, false).Return(nil).Once()
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig1).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil).Once()
 
 	// Setup expectations for the second target (failure)
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser2", "testpass2", "localhost", "3307", "testdb2",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
+	targetConfig2 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser2",
+		Password: "testpass2",
+		Host:     "localhost",
+		Port:     "3307",
+		DBName:   "testdb2",
+	}
 
-	mockSqldef.On("Execute", ctx, false).Return(assert.AnError).Once()
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig2).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", assert.AnError).Once()
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -526,11 +631,11 @@ func TestPlugin_executeApplyStage_MultipleTargetsWithFailure(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -571,7 +676,7 @@ func TestPlugin_executeApplyStage_MultipleTargetsWithFailure(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert failure - should fail when any target fails
 	assert.Equal(t, sdk.StageStatusFailure, status)
@@ -592,23 +697,31 @@ func TestPlugin_executeApplyStage_BinaryCachingAcrossTargets(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for both targets - same DB type should reuse binary
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser1", "testpass1", "localhost", "3306", "testdb1",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
-
-	mockSqldef.On("Execute", ctx, false).Return(nil).Once()
+	targetConfig1 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser1",
+		Password: "testpass1",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb1",
+	}
 
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser2", "testpass2", "localhost", "3307", "testdb2",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // Same execPath should be reused
-	).Return().Once()
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig1).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil).Once()
+
+	targetConfig2 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser2",
+		Password: "testpass2",
+		Host:     "localhost",
+		Port:     "3307",
+		DBName:   "testdb2",
+	}
 
-	mockSqldef.On("Execute", ctx, false).Return(nil).Once()
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig2).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, false).Return("", nil).Once()
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -616,11 +729,11 @@ func TestPlugin_executeApplyStage_BinaryCachingAcrossTargets(t *testing.T) {
 			StageName:   sqldefStageApply,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -661,7 +774,7 @@ func TestPlugin_executeApplyStage_BinaryCachingAcrossTargets(t *testing.T) {
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
 	// Execute the apply stage
-	status := plugin.executeApplyStage(ctx, deployTargets, input)
+	status := plugin.executeApplyStage(ctx, newFakeMetadataStore(), deployTargets, input)
 
 	// Assert success - binary caching should work correctly
 	assert.Equal(t, sdk.StageStatusSuccess, status)
@@ -669,3 +782,42 @@ func TestPlugin_executeApplyStage_BinaryCachingAcrossTargets(t *testing.T) {
 	// Verify all mock expectations were met
 	mockSqldef.AssertExpectations(t)
 }
+
+func TestPlugin_executeApplyStage_SkipsTargetsWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	schemaFiles := []string{filepath.Join("testdata", "app", "schema.sql")}
+
+	changedConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/changed.db",
+	}
+	unchangedConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/unchanged.db",
+	}
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, changedConfig, schemaFiles, true).Return(createUsersOutput, nil).Once()
+	mockSqldef.On("ShowCurrentSchema", ctx, changedConfig).Return("", nil).Once()
+	mockSqldef.On("Execute", ctx, changedConfig, schemaFiles, false).Return(createUsersOutput, nil).Once()
+	mockSqldef.On("Execute", ctx, unchangedConfig, schemaFiles, true).Return("-- Nothing is modified --\n", nil).Once()
+
+	deployTargets := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "changed", Config: changedConfig},
+		{Name: "unchanged", Config: unchangedConfig},
+	}
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	store := newFakeMetadataStore()
+	status := plugin.executeApplyStage(ctx, store, deployTargets, newDestructiveStageInput(t, sqldefStageApply, config.DestructiveChangePolicyDeny))
+
+	assert.Equal(t, sdk.StageStatusSuccess, status)
+	mockSqldef.AssertExpectations(t)
+
+	// The schema of the target without changes is not exported since it is not applied.
+	_, found, err := store.GetDeploymentPluginMetadata(ctx, preApplySchemaKey("unchanged"))
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
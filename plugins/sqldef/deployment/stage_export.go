@@ -0,0 +1,115 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
+)
+
+// executeExportStage exports the current schema of a deploy target so that it can be committed as the initial schema file.
+// The schema is shown in the stage log and stored as an application object.
+func (p *Plugin) executeExportStage(ctx context.Context, store objectStore, dts []*sdk.DeployTarget[config.DeployTargetConfig], input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
+	lp := input.Client.LogPersister()
+	lp.Info("Start exporting the current schema")
+
+	stageConfig, err := decodeStageOptions[config.SqldefExportStageOptions](input.Request.StageConfig)
+	if err != nil {
+		lp.Errorf("Failed to unmarshal stage config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	dt, err := exportTarget(dts, stageConfig.Target)
+	if err != nil {
+		lp.Errorf("Failed while choosing the deploy target to export (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	lp.Infof("Deploy Target [%s]: %s", dt.Name, describeTarget(dt.Config))
+
+	if err := dt.Config.Validate(); err != nil {
+		lp.Errorf("Invalid deploy target config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	// Currently, we create them every time the stage is executed because we can't pass input.Client.toolRegistry to the plugin when starting the plugin.
+	toolRegistry := toolRegistryPkg.NewRegistry(input.Client.ToolRegistry())
+	sqlDefPath, err := toolRegistry.DownloadBinary(ctx, dt.Config.DbType, "")
+	if err != nil {
+		lp.Errorf("Failed while getting Sqldef tool (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	schema, err := p.Sqldef.ShowCurrentSchema(ctx, sqldefTarget(dt.Config, sqlDefPath, applicationSpec(input.Request.TargetDeploymentSource)))
+	if err != nil {
+		lp.Errorf("Failed while exporting the current schema (%v)", err)
+		return sdk.StageStatusFailure
+	}
+	schema = normalizeSchema(schema)
+	if schema == "" {
+		lp.Infof("Deploy Target [%s]: the database has no objects to export", dt.Name)
+	}
+
+	key := exportedSchemaKey(dt.Name)
+	if err := store.PutApplicationSharedObject(ctx, key, []byte(schema)); err != nil {
+		lp.Errorf("Failed while storing the exported schema (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	lp.Infof("Exported schema of Deploy Target [%s]:", dt.Name)
+	lp.Write([]byte(schema))
+	lp.Successf("Stored the exported schema as %s, commit it as the schema file to manage the database", key)
+	return sdk.StageStatusSuccess
+}
+
+// exportTarget returns the deploy target with the given name, or the only deploy target when the name is empty.
+func exportTarget(dts []*sdk.DeployTarget[config.DeployTargetConfig], name string) (*sdk.DeployTarget[config.DeployTargetConfig], error) {
+	if name == "" {
+		switch len(dts) {
+		case 0:
+			return nil, errors.New("no deploy target")
+		case 1:
+			return dts[0], nil
+		default:
+			return nil, fmt.Errorf("target must be set since there are %d deploy targets", len(dts))
+		}
+	}
+	for _, dt := range dts {
+		if dt.Name == name {
+			return dt, nil
+		}
+	}
+	return nil, fmt.Errorf("deploy target %s is not found", name)
+}
+
+// normalizeSchema removes the trailing spaces of each line and the blank lines at the end,
+// so that the exported schema can be committed as it is.
+func normalizeSchema(schema string) string {
+	lines := strings.Split(schema, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	schema = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if schema == "" {
+		return ""
+	}
+	return schema + "\n"
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// fakeObjectStore is an in-memory objectStore.
+type fakeObjectStore struct {
+	objects map[string]string
+}
+
+func (s *fakeObjectStore) PutApplicationSharedObject(_ context.Context, key string, object []byte) error {
+	s.objects[key] = string(object)
+	return nil
+}
+
+func TestNormalizeSchema(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "CREATE TABLE users (\n  id int\n);\n", normalizeSchema("CREATE TABLE users (  \r\n  id int\t\n);\n\n\n"))
+	assert.Equal(t, "", normalizeSchema("\n\n"))
+}
+
+func TestPlugin_executeExportStage(t *testing.T) {
+	t.Parallel()
+
+	const exported = "CREATE TABLE `users` (\n  `id` int NOT NULL\n);  \n\n"
+	config1 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db1"}
+	config2 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db2"}
+
+	testcases := []struct {
+		name        string
+		stageConfig string
+		dts         []*sdk.DeployTarget[config.DeployTargetConfig]
+		expected    sdk.StageStatus
+		expectedKey string
+	}{
+		{
+			name:        "exports the only deploy target",
+			dts:         []*sdk.DeployTarget[config.DeployTargetConfig]{{Name: "db-1", Config: config1}},
+			expected:    sdk.StageStatusSuccess,
+			expectedKey: "exported-schema/db-1.sql",
+		},
+		{
+			name:        "exports the chosen deploy target",
+			stageConfig: `{"target": "db-2"}`,
+			dts: []*sdk.DeployTarget[config.DeployTargetConfig]{
+				{Name: "db-1", Config: config1},
+				{Name: "db-2", Config: config2},
+			},
+			expected:    sdk.StageStatusSuccess,
+			expectedKey: "exported-schema/db-2.sql",
+		},
+		{
+			name: "fails without target for multiple deploy targets",
+			dts: []*sdk.DeployTarget[config.DeployTargetConfig]{
+				{Name: "db-1", Config: config1},
+				{Name: "db-2", Config: config2},
+			},
+			expected: sdk.StageStatusFailure,
+		},
+		{
+			name:        "fails for unknown deploy target",
+			stageConfig: `{"target": "db-3"}`,
+			dts:         []*sdk.DeployTarget[config.DeployTargetConfig]{{Name: "db-1", Config: config1}},
+			expected:    sdk.StageStatusFailure,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockSqldef := &MockSqldefProvider{}
+			mockSqldef.On("ShowCurrentSchema", ctx, config2).Return(exported, nil).Maybe()
+			mockSqldef.On("ShowCurrentSchema", ctx, config1).Return(exported, nil).Maybe()
+
+			input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+				Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+					StageName:   sqldefStageExport,
+					StageConfig: []byte(tc.stageConfig),
+				},
+				Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+			}
+
+			store := &fakeObjectStore{objects: make(map[string]string)}
+			plugin := createPluginWithMockSqldef(mockSqldef)
+			status := plugin.executeExportStage(ctx, store, tc.dts, input)
+
+			assert.Equal(t, tc.expected, status)
+			if tc.expectedKey == "" {
+				assert.Empty(t, store.objects)
+				return
+			}
+			assert.Equal(t, map[string]string{tc.expectedKey: "CREATE TABLE `users` (\n  `id` int NOT NULL\n);\n"}, store.objects)
+		})
+	}
+}
+
+func TestPlugin_executeExportStage_Error(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	targetConfig := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db1"}
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig).Return("", assert.AnError)
+
+	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName: sqldefStageExport,
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+	}
+
+	store := &fakeObjectStore{objects: make(map[string]string)}
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	status := plugin.executeExportStage(ctx, store, []*sdk.DeployTarget[config.DeployTargetConfig]{{Name: "db-1", Config: targetConfig}}, input)
+
+	assert.Equal(t, sdk.StageStatusFailure, status)
+	assert.Empty(t, store.objects)
+	mockSqldef.AssertExpectations(t)
+}
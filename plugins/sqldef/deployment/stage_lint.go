@@ -0,0 +1,84 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// executeLintStage checks the schema files against the lint rules in the application config.
+// It only parses the schema files, so no connection to the deploy targets is needed.
+func (p *Plugin) executeLintStage(ctx context.Context, input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
+	lp := input.Client.LogPersister()
+	lp.Info("Start linting the schema")
+
+	ds := input.Request.TargetDeploymentSource
+	spec := applicationSpec(ds)
+	if spec == nil || !spec.Lint.Enabled() {
+		lp.Error("lint rules must be set in the application config to use SQLDEF_LINT")
+		return sdk.StageStatusFailure
+	}
+
+	schemaFiles, err := resolveSchemaFiles(ds.ApplicationDirectory, spec)
+	if err != nil {
+		lp.Errorf("Failed while finding schema files (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	var (
+		files []string
+		stmts []sqlStatement
+	)
+	for _, f := range schemaFiles {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			lp.Errorf("Failed while reading the schema file (%v)", err)
+			return sdk.StageStatusFailure
+		}
+		name, err := filepath.Rel(ds.ApplicationDirectory, f)
+		if err != nil {
+			name = f
+		}
+		files = append(files, name)
+		stmts = append(stmts, splitSQLStatements(name, string(data))...)
+	}
+
+	tables := parseLintTables(stmts)
+	violations := lintSchema(spec.Lint, files, tables)
+
+	var errs, warnings int
+	for _, v := range violations {
+		if v.Severity == config.LintSeverityError {
+			lp.Errorf("error: %s", v)
+			errs++
+		} else {
+			lp.Infof("warning: %s", v)
+			warnings++
+		}
+	}
+
+	if errs > 0 {
+		lp.Errorf("Found %d error(s) and %d warning(s) in %d table(s)", errs, warnings, len(tables))
+		return sdk.StageStatusFailure
+	}
+	lp.Successf("Found no errors and %d warning(s) in %d table(s)", warnings, len(tables))
+	return sdk.StageStatusSuccess
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+)
+
+// migrationFileRegex matches the file name of a migration script, e.g. "20250101120000_backfill_users.sql".
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+// executeMigrateStage runs the migration scripts which have not been applied to each deploy target yet.
+// The stage can be placed before or after SQLDEF_APPLY depending on whether the scripts need the new schema.
+func (p *Plugin) executeMigrateStage(ctx context.Context, dts []*sdk.DeployTarget[config.DeployTargetConfig], input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
+	lp := input.Client.LogPersister()
+	lp.Info("Start running the migration scripts")
+
+	stageConfig, err := decodeStageOptions[config.SqldefMigrateStageOptions](input.Request.StageConfig)
+	if err != nil {
+		lp.Errorf("Failed to unmarshal stage config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	ds := input.Request.TargetDeploymentSource
+	spec := applicationSpec(ds)
+	if spec == nil || !spec.Migrations.Enabled() {
+		lp.Error("migrations.dir must be set in the application config to use SQLDEF_MIGRATE")
+		return sdk.StageStatusFailure
+	}
+	table := spec.Migrations.TableOrDefault()
+
+	migrations, err := loadMigrations(filepath.Join(ds.ApplicationDirectory, spec.Migrations.Dir))
+	if err != nil {
+		lp.Errorf("Failed while loading the migration scripts (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
+	results := runTargets(lp, executionConfig(spec), targetNames(dts), func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		dt := dts[i]
+		lp.Infof("Deploy Target [%s]: %s", dt.Name, describeTarget(dt.Config))
+
+		if err := dt.Config.Validate(); err != nil {
+			return "", fmt.Errorf("invalid deploy target config: %w", err)
+		}
+
+		applied, err := p.Migrator.AppliedMigrations(ctx, dt.Config, table)
+		if err != nil {
+			return "", fmt.Errorf("failed while listing the applied migration scripts: %w", err)
+		}
+
+		pending, err := pendingMigrations(migrations, applied)
+		if err != nil {
+			return "", fmt.Errorf("failed while verifying the applied migration scripts: %w", err)
+		}
+		if len(pending) == 0 {
+			lp.Infof("Deploy Target [%s]: no pending migration scripts", dt.Name)
+			return targetStatusNoChange, nil
+		}
+
+		if stageConfig.DryRun {
+			lp.Infof("Deploy Target [%s]: %d pending migration scripts would be run:", dt.Name, len(pending))
+			for _, m := range pending {
+				lp.Infof("  %s", m.Name)
+			}
+			return targetStatusSuccess, nil
+		}
+
+		for _, m := range pending {
+			lp.Infof("Deploy Target [%s]: running %s", dt.Name, m.Name)
+			if err := p.Migrator.ApplyMigration(ctx, dt.Config, table, m); err != nil {
+				return "", fmt.Errorf("failed while running the migration script %s: %w", m.Name, err)
+			}
+		}
+		lp.Infof("Deploy Target [%s]: ran %d migration scripts", dt.Name, len(pending))
+		return targetStatusSuccess, nil
+	})
+	if !reportTargetResults(lp, results) {
+		return sdk.StageStatusFailure
+	}
+
+	return sdk.StageStatusSuccess
+}
+
+// loadMigrations returns the migration scripts in the given directory ordered by their versions.
+func loadMigrations(dir string) ([]provider.Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		migrations []provider.Migration
+		seen       = make(map[int64]string)
+	)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		m := migrationFileRegex.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration script name %s, must be <version>_<description>.sql", e.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid version of migration script %s", e.Name())
+		}
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration scripts %s and %s have the same version %d", prev, e.Name(), version)
+		}
+		seen[version] = e.Name()
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, provider.Migration{
+			Version:  version,
+			Name:     e.Name(),
+			Script:   string(data),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// pendingMigrations returns the migration scripts which have not been applied yet.
+// It fails when an applied script has been changed or removed,
+// or when a new script is older than the latest applied one since the scripts must run in order.
+func pendingMigrations(migrations []provider.Migration, applied []provider.AppliedMigration) ([]provider.Migration, error) {
+	byVersion := make(map[int64]provider.Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var (
+		errs   []error
+		done   = make(map[int64]struct{}, len(applied))
+		latest int64
+	)
+	for _, a := range applied {
+		done[a.Version] = struct{}{}
+		latest = max(latest, a.Version)
+
+		m, ok := byVersion[a.Version]
+		if !ok {
+			errs = append(errs, fmt.Errorf("applied migration script %s is missing", a.Name))
+			continue
+		}
+		if m.Checksum != a.Checksum {
+			errs = append(errs, fmt.Errorf("applied migration script %s has been changed, checksum %s is expected but got %s", m.Name, a.Checksum, m.Checksum))
+		}
+	}
+
+	var pending []provider.Migration
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		if m.Version < latest {
+			errs = append(errs, fmt.Errorf("migration script %s is older than the latest applied version %d", m.Name, latest))
+			continue
+		}
+		pending = append(pending, m)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
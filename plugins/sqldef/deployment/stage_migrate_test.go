@@ -0,0 +1,332 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
+)
+
+// MockMigrationProvider is a mock implementation of provider.MigrationProvider
+type MockMigrationProvider struct {
+	mock.Mock
+}
+
+func (m *MockMigrationProvider) AppliedMigrations(ctx context.Context, cfg config.DeployTargetConfig, table string) ([]provider.AppliedMigration, error) {
+	args := m.Called(ctx, cfg, table)
+	applied, _ := args.Get(0).([]provider.AppliedMigration)
+	return applied, args.Error(1)
+}
+
+func (m *MockMigrationProvider) ApplyMigration(ctx context.Context, cfg config.DeployTargetConfig, table string, migration provider.Migration) error {
+	args := m.Called(ctx, cfg, table, migration.Name)
+	return args.Error(0)
+}
+
+// checksumOf returns the checksum of the script written by writeFiles with the given path.
+func checksumOf(name string) string {
+	sum := sha256.Sum256([]byte("-- " + name + "\n"))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		files    []string
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "ordered by version",
+			files:    []string{"10_c.sql", "2_b.sql", "1_a.sql", "README.md", "sub/3_d.sql"},
+			expected: []string{"1_a.sql", "2_b.sql", "10_c.sql"},
+		},
+		{
+			name:    "invalid name",
+			files:   []string{"1_a.sql", "backfill.sql"},
+			wantErr: "invalid migration script name backfill.sql",
+		},
+		{
+			name:    "zero version",
+			files:   []string{"0_a.sql"},
+			wantErr: "invalid version of migration script 0_a.sql",
+		},
+		{
+			name:    "duplicated version",
+			files:   []string{"1_a.sql", "01_b.sql"},
+			wantErr: "have the same version 1",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files...)
+
+			migrations, err := loadMigrations(dir)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(migrations))
+			for _, m := range migrations {
+				names = append(names, m.Name)
+				assert.Equal(t, checksumOf(m.Name), m.Checksum)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	t.Parallel()
+
+	migrations := []provider.Migration{
+		{Version: 1, Name: "1_a.sql", Checksum: "a"},
+		{Version: 2, Name: "2_b.sql", Checksum: "b"},
+		{Version: 3, Name: "3_c.sql", Checksum: "c"},
+	}
+
+	testcases := []struct {
+		name     string
+		applied  []provider.AppliedMigration
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "nothing applied",
+			expected: []string{"1_a.sql", "2_b.sql", "3_c.sql"},
+		},
+		{
+			name: "some applied",
+			applied: []provider.AppliedMigration{
+				{Version: 1, Name: "1_a.sql", Checksum: "a"},
+			},
+			expected: []string{"2_b.sql", "3_c.sql"},
+		},
+		{
+			name: "all applied",
+			applied: []provider.AppliedMigration{
+				{Version: 1, Name: "1_a.sql", Checksum: "a"},
+				{Version: 2, Name: "2_b.sql", Checksum: "b"},
+				{Version: 3, Name: "3_c.sql", Checksum: "c"},
+			},
+		},
+		{
+			name: "applied script changed",
+			applied: []provider.AppliedMigration{
+				{Version: 1, Name: "1_a.sql", Checksum: "old"},
+			},
+			wantErr: "applied migration script 1_a.sql has been changed",
+		},
+		{
+			name: "applied script removed",
+			applied: []provider.AppliedMigration{
+				{Version: 1, Name: "1_a.sql", Checksum: "a"},
+				{Version: 5, Name: "5_e.sql", Checksum: "e"},
+			},
+			wantErr: "applied migration script 5_e.sql is missing",
+		},
+		{
+			name: "new script older than applied",
+			applied: []provider.AppliedMigration{
+				{Version: 1, Name: "1_a.sql", Checksum: "a"},
+				{Version: 3, Name: "3_c.sql", Checksum: "c"},
+			},
+			wantErr: "migration script 2_b.sql is older than the latest applied version 3",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			pending, err := pendingMigrations(migrations, tc.applied)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for _, m := range pending {
+				names = append(names, m.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func newMigrateStageInput(t *testing.T, appDir, stageConfig string, migrations config.MigrationsConfig) *sdk.ExecuteStageInput[config.ApplicationConfigSpec] {
+	t.Helper()
+	ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+		ApplicationDirectory: appDir,
+		CommitHash:           "0123456789",
+		ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+			Spec: &config.ApplicationConfigSpec{Migrations: migrations},
+		},
+	}
+	return &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:               sqldefStageMigrate,
+			StageConfig:             []byte(stageConfig),
+			RunningDeploymentSource: ds,
+			TargetDeploymentSource:  ds,
+			Deployment: sdk.Deployment{
+				PipedID:       "piped-id",
+				ApplicationID: "app-id",
+			},
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+	}
+}
+
+func TestPlugin_executeMigrateStage(t *testing.T) {
+	t.Parallel()
+
+	targetConfig := config.DeployTargetConfig{
+		DbType: config.DBTypeSQLite,
+		Path:   "/tmp/test.db",
+	}
+	applied := []provider.AppliedMigration{
+		{Version: 1, Name: "1_create_admin.sql", Checksum: checksumOf(filepath.Join("migrations", "1_create_admin.sql"))},
+	}
+
+	testcases := []struct {
+		name        string
+		stageConfig string
+		migrations  config.MigrationsConfig
+		applied     []string
+		expected    sdk.StageStatus
+	}{
+		{
+			name:       "runs pending scripts",
+			migrations: config.MigrationsConfig{Dir: "migrations"},
+			applied:    []string{"2_backfill_users.sql", "3_backfill_posts.sql"},
+			expected:   sdk.StageStatusSuccess,
+		},
+		{
+			name:        "lists pending scripts in dry run",
+			stageConfig: `{"dryRun": true}`,
+			migrations:  config.MigrationsConfig{Dir: "migrations"},
+			expected:    sdk.StageStatusSuccess,
+		},
+		{
+			name:       "fails without migrations config",
+			migrations: config.MigrationsConfig{},
+			expected:   sdk.StageStatusFailure,
+		},
+		{
+			name:       "fails when the directory does not exist",
+			migrations: config.MigrationsConfig{Dir: "no-such-dir"},
+			expected:   sdk.StageStatusFailure,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			appDir := t.TempDir()
+			writeFiles(t, appDir,
+				filepath.Join("migrations", "1_create_admin.sql"),
+				filepath.Join("migrations", "2_backfill_users.sql"),
+				filepath.Join("migrations", "3_backfill_posts.sql"),
+			)
+
+			mockMigrator := &MockMigrationProvider{}
+			mockMigrator.On("AppliedMigrations", ctx, targetConfig, config.DefaultMigrationsTable).Return(applied, nil).Maybe()
+			for _, name := range tc.applied {
+				mockMigrator.On("ApplyMigration", ctx, targetConfig, config.DefaultMigrationsTable, name).Return(nil).Once()
+			}
+
+			plugin := &Plugin{Migrator: mockMigrator}
+			input := newMigrateStageInput(t, appDir, tc.stageConfig, tc.migrations)
+			status := plugin.executeMigrateStage(ctx, []*sdk.DeployTarget[config.DeployTargetConfig]{
+				{Name: "test-sqlite", Config: targetConfig},
+			}, input)
+
+			assert.Equal(t, tc.expected, status)
+			mockMigrator.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPlugin_executeMigrateStage_MultipleTargets(t *testing.T) {
+	t.Parallel()
+
+	failing := config.DeployTargetConfig{DbType: config.DBTypeSQLite, Path: "/tmp/failing.db"}
+	healthy := config.DeployTargetConfig{DbType: config.DBTypeSQLite, Path: "/tmp/healthy.db"}
+
+	testcases := []struct {
+		name    string
+		onError config.OnErrorPolicy
+		ran     bool
+	}{
+		{name: "fail fast skips the remaining targets", onError: config.OnErrorFailFast},
+		{name: "continue runs the remaining targets", onError: config.OnErrorContinue, ran: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			appDir := t.TempDir()
+			writeFiles(t, appDir, filepath.Join("migrations", "1_create_admin.sql"))
+
+			mockMigrator := &MockMigrationProvider{}
+			mockMigrator.On("AppliedMigrations", ctx, failing, config.DefaultMigrationsTable).Return([]provider.AppliedMigration(nil), errors.New("connection refused")).Once()
+			if tc.ran {
+				mockMigrator.On("AppliedMigrations", ctx, healthy, config.DefaultMigrationsTable).Return([]provider.AppliedMigration(nil), nil).Once()
+				mockMigrator.On("ApplyMigration", ctx, healthy, config.DefaultMigrationsTable, "1_create_admin.sql").Return(nil).Once()
+			}
+
+			input := newMigrateStageInput(t, appDir, "", config.MigrationsConfig{Dir: "migrations"})
+			input.Request.TargetDeploymentSource.ApplicationConfig.Spec.Execution = config.ExecutionConfig{OnError: tc.onError}
+
+			plugin := &Plugin{Migrator: mockMigrator}
+			status := plugin.executeMigrateStage(ctx, []*sdk.DeployTarget[config.DeployTargetConfig]{
+				{Name: "failing", Config: failing},
+				{Name: "healthy", Config: healthy},
+			}, input)
+
+			assert.Equal(t, sdk.StageStatusFailure, status)
+			mockMigrator.AssertExpectations(t)
+		})
+	}
+}
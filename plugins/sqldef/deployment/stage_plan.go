@@ -16,6 +16,10 @@ package deployment
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sync"
+
 	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
 	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
 
@@ -26,36 +30,74 @@ func (p *Plugin) executePlanStage(ctx context.Context, dts []*sdk.DeployTarget[c
 	lp := input.Client.LogPersister()
 	lp.Info("Start planning the schema deployment")
 
+	stageConfig, err := decodeStageOptions[config.SqldefPlanStageOptions](input.Request.StageConfig)
+	if err != nil {
+		lp.Errorf("Failed to unmarshal stage config (%v)", err)
+		return sdk.StageStatusFailure
+	}
+
 	// Currently, we create them every time the stage is executed beucause we can't pass input.Client.toolRegistry to the plugin when starting the plugin.
 	toolRegistry := toolRegistryPkg.NewRegistry(input.Client.ToolRegistry())
 
-	for _, dt := range dts {
-		lp.Infof("Deploy Target [%s]: host=%s, port=%s, db=%s, schemaFile=%s",
-			dt.Name,
-			dt.Config.Host,
-			dt.Config.Port,
-			dt.Config.DBName,
-		)
+	spec := applicationSpec(input.Request.TargetDeploymentSource)
+
+	var (
+		mu          sync.Mutex
+		destructive int
+	)
+	results := runTargets(lp, executionConfig(spec), targetNames(dts), func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		dt := dts[i]
+		lp.Infof("Deploy Target [%s]: %s", dt.Name, describeTarget(dt.Config))
+
+		if err := dt.Config.Validate(); err != nil {
+			return "", fmt.Errorf("invalid deploy target config: %w", err)
+		}
 
 		sqlDefPath, err := toolRegistry.DownloadBinary(ctx, dt.Config.DbType, "")
 		if err != nil {
-			lp.Errorf("Failed while getting Sqldef tool (%v)", err)
-			return sdk.StageStatusFailure
+			return "", fmt.Errorf("failed while getting Sqldef tool: %w", err)
 		}
 
-		appDir := input.Request.TargetDeploymentSource.ApplicationDirectory
-		schemaPath, err := findFirstSQLFile(appDir)
+		schemaFiles, err := resolveSchemaFiles(input.Request.TargetDeploymentSource.ApplicationDirectory, spec)
 		if err != nil {
-			lp.Errorf("Failed while finding schema file (%v)", err)
-			return sdk.StageStatusFailure
+			return "", fmt.Errorf("failed while finding schema files: %w", err)
 		}
 
-		p.Sqldef.Init(lp, dt.Config.Username, dt.Config.Password, dt.Config.Host, dt.Config.Port, dt.Config.DBName, schemaPath, sqlDefPath)
-
-		if err := p.Sqldef.Execute(ctx, true); err != nil {
-			lp.Errorf("Failed while plan the deployment (%v)", err)
-			return sdk.StageStatusFailure
+		result, err := p.Sqldef.Execute(ctx, lp, sqldefTarget(dt.Config, sqlDefPath, spec), schemaFiles, true)
+		if err != nil {
+			return "", fmt.Errorf("failed while planning the deployment: %w", err)
 		}
+		reportChanges(lp, dt.Name, result)
+		if result.NoChanges() {
+			return targetStatusNoChange, nil
+		}
+
+		changes := classifyDestructiveChanges(result.Statements)
+		reportDestructiveChanges(lp, dt.Name, changes)
+		mu.Lock()
+		destructive += len(changes)
+		mu.Unlock()
+		return targetStatusSuccess, nil
+	})
+	if !reportTargetResults(lp, results) {
+		return sdk.StageStatusFailure
+	}
+
+	noChanges := !slices.ContainsFunc(results, func(r targetResult) bool { return r.Status == targetStatusSuccess })
+	if noChanges && stageConfig.ExitOnNoChanges {
+		return sdk.StageStatusExited
+	}
+
+	if destructive == 0 {
+		return sdk.StageStatusSuccess
+	}
+
+	// The plan only reports them, whether they are applied is decided by the apply stage.
+	switch policy := destructivePolicy(spec); policy {
+	case config.DestructiveChangePolicyDeny:
+		lp.Infof("Found %d destructive changes, the apply stage will fail unless destructiveChanges is set to %q or %q", destructive, config.DestructiveChangePolicyAllow, config.DestructiveChangePolicyApproval)
+	case config.DestructiveChangePolicyApproval:
+		lp.Infof("Found %d destructive changes, they will be applied after a manual approval", destructive)
 	}
 
 	return sdk.StageStatusSuccess
@@ -22,20 +22,21 @@ type MockSqldefProvider struct {
 	mock.Mock
 }
 
-func (m *MockSqldefProvider) Init(logger sdk.StageLogPersister, username, password, host, port, dbName, schemaFilePath, execPath string) {
-	m.Called(logger, username, password, host, port, dbName, schemaFilePath, execPath)
-}
-
-func (m *MockSqldefProvider) ShowCurrentSchema(ctx context.Context) (string, error) {
-	args := m.Called(ctx)
+// The calls are recorded with the config of the target since the path to the sqldef binary depends on the environment.
+func (m *MockSqldefProvider) ShowCurrentSchema(ctx context.Context, target provider.Target) (string, error) {
+	args := m.Called(ctx, target.Config)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockSqldefProvider) Execute(ctx context.Context, dryRun bool) error {
-	args := m.Called(ctx, dryRun)
-	return args.Error(0)
+// The output of sqldef is given as the return value for ease of writing the expectations.
+func (m *MockSqldefProvider) Execute(ctx context.Context, logger sdk.StageLogPersister, target provider.Target, schemaFiles []string, dryRun bool) (provider.ExecuteResult, error) {
+	args := m.Called(ctx, target.Config, schemaFiles, dryRun)
+	return provider.NewExecuteResult(args.String(0)), args.Error(1)
 }
 
+// createUsersOutput is the output of the sqldef dry run for testdata/app/schema.sql against an empty database.
+const createUsersOutput = "CREATE TABLE users (\n    id INT PRIMARY KEY,\n    name VARCHAR(255) NOT NULL\n);\n"
+
 // createPluginWithMockSqldef creates a Plugin instance with a mock sqldef provider
 func createPluginWithMockSqldef(mockProvider provider.SqldefProvider) *Plugin {
 	return &Plugin{
@@ -55,14 +56,16 @@ func TestPlugin_executePlanStage_HappyPath(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser", "testpass", "localhost", "3306", "testdb",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return()
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
 
-	mockSqldef.On("Execute", ctx, true).Return(nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return("", nil)
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -70,11 +73,11 @@ func TestPlugin_executePlanStage_HappyPath(t *testing.T) {
 			StageName:   sqldefStagePlan,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -130,11 +133,11 @@ func TestPlugin_executePlanStage_EmptySchemaFileName(t *testing.T) {
 			StageName:   sqldefStagePlan,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -163,18 +166,16 @@ func TestPlugin_executePlanStage_EmptySchemaFileName(t *testing.T) {
 	// Create plugin with mock sqldef provider
 	plugin := createPluginWithMockSqldef(mockSqldef)
 
-	mockSqldef.On("Init",
-		mock.Anything, // log persister
-		"testuser",
-		"testpass",
-		"localhost",
-		"3306",
-		"testdb",
-		"testdata/schema.sql",
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return(nil)
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
 
-	mockSqldef.On("Execute", mock.Anything, true).Return(errors.New("execute failed."))
+	mockSqldef.On("Execute", mock.Anything, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return("", errors.New("execute failed."))
 
 	// Execute the plan stage
 	status := plugin.executePlanStage(ctx, deployTargets, input)
@@ -203,11 +204,11 @@ func TestPlugin_executePlanStage_UnsupportedDBType(t *testing.T) {
 			StageName:   sqldefStagePlan,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -223,7 +224,7 @@ func TestPlugin_executePlanStage_UnsupportedDBType(t *testing.T) {
 		{
 			Name: "test-postgres",
 			Config: config.DeployTargetConfig{
-				DbType:   config.DBTypePostgres, // Unsupported type
+				DbType:   config.DBType("oracle"), // Unsupported type
 				Username: "testuser",
 				Password: "testpass",
 				Host:     "localhost",
@@ -258,15 +259,17 @@ func TestPlugin_executePlanStage_SqldefExecuteError(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser", "testpass", "localhost", "3306", "testdb",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return()
+	targetConfig := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser",
+		Password: "testpass",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb",
+	}
 
 	// Mock Execute to return an error
-	mockSqldef.On("Execute", ctx, true).Return(nil)
+	mockSqldef.On("Execute", ctx, targetConfig, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return("", nil)
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -274,11 +277,11 @@ func TestPlugin_executePlanStage_SqldefExecuteError(t *testing.T) {
 			StageName:   sqldefStagePlan,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -329,23 +332,27 @@ func TestPlugin_executePlanStage_MultipleTargets(t *testing.T) {
 	mockSqldef := &MockSqldefProvider{}
 
 	// Setup expectations for the mock - should be called twice for two targets
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser1", "testpass1", "localhost", "3306", "testdb1",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
+	targetConfig1 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser1",
+		Password: "testpass1",
+		Host:     "localhost",
+		Port:     "3306",
+		DBName:   "testdb1",
+	}
 
-	mockSqldef.On("Execute", ctx, true).Return(nil).Once()
+	mockSqldef.On("Execute", ctx, targetConfig1, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return("", nil).Once()
 
-	mockSqldef.On("Init",
-		mock.AnythingOfType("logpersistertest.TestLogPersister"),
-		"testuser2", "testpass2", "localhost", "3307", "testdb2",
-		filepath.Join("testdata", "schema.sql"),
-		mock.AnythingOfType("string"), // execPath from tool registry
-	).Return().Once()
+	targetConfig2 := config.DeployTargetConfig{
+		DbType:   config.DBTypeMySQL,
+		Username: "testuser2",
+		Password: "testpass2",
+		Host:     "localhost",
+		Port:     "3307",
+		DBName:   "testdb2",
+	}
 
-	mockSqldef.On("Execute", ctx, true).Return(nil).Once()
+	mockSqldef.On("Execute", ctx, targetConfig2, []string{filepath.Join("testdata", "app", "schema.sql")}, true).Return("", nil).Once()
 
 	// Prepare the input
 	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
@@ -353,11 +360,11 @@ func TestPlugin_executePlanStage_MultipleTargets(t *testing.T) {
 			StageName:   sqldefStagePlan,
 			StageConfig: []byte(``),
 			RunningDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			TargetDeploymentSource: sdk.DeploymentSource[config.ApplicationConfigSpec]{
-				ApplicationDirectory: filepath.Join("testdata"),
+				ApplicationDirectory: filepath.Join("testdata", "app"),
 				CommitHash:           "0123456789",
 			},
 			Deployment: sdk.Deployment{
@@ -406,3 +413,65 @@ func TestPlugin_executePlanStage_MultipleTargets(t *testing.T) {
 	// Verify all mock expectations were met
 	mockSqldef.AssertExpectations(t)
 }
+
+func TestPlugin_executePlanStage_ExitOnNoChanges(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		stageConfig string
+		output      string
+		expected    sdk.StageStatus
+	}{
+		{
+			name:        "exits when no changes",
+			stageConfig: `{"exitOnNoChanges": true}`,
+			output:      "-- Nothing is modified --\n",
+			expected:    sdk.StageStatusExited,
+		},
+		{
+			name:        "succeeds when there are changes",
+			stageConfig: `{"exitOnNoChanges": true}`,
+			output:      createUsersOutput,
+			expected:    sdk.StageStatusSuccess,
+		},
+		{
+			name:        "succeeds when no changes without exitOnNoChanges",
+			stageConfig: `{}`,
+			output:      "-- Nothing is modified --\n",
+			expected:    sdk.StageStatusSuccess,
+		},
+		{
+			name:        "fails with invalid stage config",
+			stageConfig: `{"exitOnNoChanges": "yes"}`,
+			expected:    sdk.StageStatusFailure,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockSqldef := &MockSqldefProvider{}
+			mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, true).Return(tc.output, nil).Maybe()
+
+			deployTargets := []*sdk.DeployTarget[config.DeployTargetConfig]{
+				{
+					Name: "test-sqlite",
+					Config: config.DeployTargetConfig{
+						DbType: config.DBTypeSQLite,
+						Path:   "/tmp/test.db",
+					},
+				},
+			}
+
+			input := newDestructiveStageInput(t, sqldefStagePlan, config.DestructiveChangePolicyDeny)
+			input.Request.StageConfig = []byte(tc.stageConfig)
+
+			plugin := createPluginWithMockSqldef(mockSqldef)
+			assert.Equal(t, tc.expected, plugin.executePlanStage(ctx, deployTargets, input))
+			mockSqldef.AssertExpectations(t)
+		})
+	}
+}
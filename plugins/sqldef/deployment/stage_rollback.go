@@ -16,47 +16,95 @@ package deployment
 
 import (
 	"context"
+	"fmt"
+	"os"
+
 	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
 	toolRegistryPkg "github.com/pipe-cd/community-plugins/plugins/sqldef/toolregistry"
 
 	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 )
 
-func (p *Plugin) executeRollbackStage(ctx context.Context, dts []*sdk.DeployTarget[config.DeployTargetConfig], input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
+func (p *Plugin) executeRollbackStage(ctx context.Context, store metadataStore, dts []*sdk.DeployTarget[config.DeployTargetConfig], input *sdk.ExecuteStageInput[config.ApplicationConfigSpec]) sdk.StageStatus {
 	lp := input.Client.LogPersister()
 	lp.Info("Start rollback the schema deployment")
 
 	// Currently, we create them every time the stage is executed because we can't pass input.Client.toolRegistry to the plugin when starting the plugin.
 	toolRegistry := toolRegistryPkg.NewRegistry(input.Client.ToolRegistry())
 
-	for _, dt := range dts {
-		lp.Infof("Deploy Target [%s]: host=%s, port=%s, db=%s",
-			dt.Name,
-			dt.Config.Host,
-			dt.Config.Port,
-			dt.Config.DBName,
-		)
+	// Only the targets changed by the apply stages are rolled back,
+	// which are the ones whose schema was exported right before applying.
+	spec := applicationSpec(input.Request.TargetDeploymentSource)
+	results := runTargets(lp, executionConfig(spec), targetNames(dts), func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		dt := dts[i]
+		lp.Infof("Deploy Target [%s]: %s", dt.Name, describeTarget(dt.Config))
+
+		if err := dt.Config.Validate(); err != nil {
+			return "", fmt.Errorf("invalid deploy target config: %w", err)
+		}
+
+		schema, found, err := store.GetDeploymentPluginMetadata(ctx, preApplySchemaKey(dt.Name))
+		if err != nil {
+			return "", fmt.Errorf("failed while getting the schema exported before applying: %w", err)
+		}
+		if !found {
+			lp.Infof("Deploy Target [%s]: no schema was exported before applying, nothing to roll back", dt.Name)
+			return targetStatusNoChange, nil
+		}
 
 		sqlDefPath, err := toolRegistry.DownloadBinary(ctx, dt.Config.DbType, "")
 		if err != nil {
-			lp.Errorf("Failed while getting Sqldef tool (%v)", err)
-			return sdk.StageStatusFailure
+			return "", fmt.Errorf("failed while getting Sqldef tool: %w", err)
 		}
 
-		appDir := input.Request.RunningDeploymentSource.ApplicationDirectory
-		schemaPath, err := findFirstSQLFile(appDir)
+		target := sqldefTarget(dt.Config, sqlDefPath, spec)
+		restored, err := p.restoreSchema(ctx, lp, target, schema)
 		if err != nil {
-			lp.Errorf("Failed while finding schema file (%v)", err)
-			return sdk.StageStatusFailure
+			return "", fmt.Errorf("failed while rolling back the deployment: %w", err)
+		}
+		if !restored {
+			return targetStatusNoChange, nil
 		}
+		return targetStatusSuccess, nil
+	})
+
+	if !reportTargetResults(lp, results) {
+		return sdk.StageStatusFailure
+	}
+	return sdk.StageStatusSuccess
+}
 
-		p.Sqldef.Init(lp, dt.Config.Username, dt.Config.Password, dt.Config.Host, dt.Config.Port, dt.Config.DBName, schemaPath, sqlDefPath)
+// restoreSchema restores the deploy target to the given schema after showing the changes by the dry run,
+// and returns false if the deploy target already has the schema.
+// It restores the schema regardless of destructiveChanges
+// since the rollback is triggered automatically and must not wait for an approval.
+func (p *Plugin) restoreSchema(ctx context.Context, lp sdk.StageLogPersister, target provider.Target, schema string) (bool, error) {
+	f, err := os.CreateTemp("", "sqldef-rollback-*.sql")
+	if err != nil {
+		return false, fmt.Errorf("failed to create the schema file: %w", err)
+	}
+	defer os.Remove(f.Name())
 
-		if err := p.Sqldef.Execute(ctx, false); err != nil {
-			lp.Errorf("Failed while applying the deployment (%v)", err)
-			return sdk.StageStatusFailure
-		}
+	if _, err := f.WriteString(schema); err != nil {
+		f.Close()
+		return false, fmt.Errorf("failed to write the schema file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("failed to write the schema file: %w", err)
 	}
 
-	return sdk.StageStatusSuccess
+	schemaFiles := []string{f.Name()}
+
+	result, err := p.Sqldef.Execute(ctx, lp, target, schemaFiles, true)
+	if err != nil {
+		return false, err
+	}
+	if result.NoChanges() {
+		return false, nil
+	}
+	if _, err := p.Sqldef.Execute(ctx, lp, target, schemaFiles, false); err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -0,0 +1,121 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// fakeMetadataStore is an in-memory metadataStore.
+type fakeMetadataStore struct {
+	metadata map[string]string
+}
+
+func newFakeMetadataStore() *fakeMetadataStore {
+	return &fakeMetadataStore{metadata: make(map[string]string)}
+}
+
+func (s *fakeMetadataStore) GetDeploymentPluginMetadata(_ context.Context, key string) (string, bool, error) {
+	v, ok := s.metadata[key]
+	return v, ok, nil
+}
+
+func (s *fakeMetadataStore) PutDeploymentPluginMetadata(_ context.Context, key, value string) error {
+	s.metadata[key] = value
+	return nil
+}
+
+func TestPlugin_executeApplyStage_ExportsPreApplySchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db1"}).Return("CREATE TABLE users (id INT);\n", nil).Once()
+	mockSqldef.On("Execute", ctx, mock.Anything, mock.Anything, false).Return("", nil)
+
+	store := newFakeMetadataStore()
+	// The schema exported by the previous apply stage of the deployment must be kept.
+	store.metadata[preApplySchemaKey("db-2")] = "CREATE TABLE old (id INT);\n"
+
+	deployTargets := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "db-1", Config: config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db1"}},
+		{Name: "db-2", Config: config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db2"}},
+	}
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	status := plugin.executeApplyStage(ctx, store, deployTargets, newDestructiveStageInput(t, sqldefStageApply, config.DestructiveChangePolicyDeny))
+
+	assert.Equal(t, sdk.StageStatusSuccess, status)
+	assert.Equal(t, map[string]string{
+		preApplySchemaKey("db-1"): "CREATE TABLE users (id INT);\n",
+		preApplySchemaKey("db-2"): "CREATE TABLE old (id INT);\n",
+	}, store.metadata)
+	mockSqldef.AssertExpectations(t)
+}
+
+func TestPlugin_executeRollbackStage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const schema = "CREATE TABLE users (id INT);\n"
+
+	mockSqldef := &MockSqldefProvider{}
+	// Only the target exported before applying is rolled back to the exported schema.
+	restored := mock.MatchedBy(func(files []string) bool {
+		if len(files) != 1 {
+			return false
+		}
+		data, err := os.ReadFile(files[0])
+		return err == nil && string(data) == schema
+	})
+	target := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db1"}
+	mockSqldef.On("Execute", ctx, target, restored, true).Return("DROP TABLE posts;\n", nil).Once()
+	mockSqldef.On("Execute", ctx, target, restored, false).Return("", nil).Once()
+
+	store := newFakeMetadataStore()
+	store.metadata[preApplySchemaKey("db-1")] = schema
+
+	deployTargets := []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "db-1", Config: config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db1"}},
+		{Name: "db-2", Config: config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db2"}},
+	}
+
+	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName: sqldefStageRollback,
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+	}
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	status := plugin.executeRollbackStage(ctx, store, deployTargets, input)
+
+	assert.Equal(t, sdk.StageStatusSuccess, status)
+	mockSqldef.AssertExpectations(t)
+}
@@ -0,0 +1,256 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// targetStatus is the result of a stage on a deploy target.
+type targetStatus string
+
+const (
+	targetStatusSuccess  targetStatus = "success"
+	targetStatusNoChange targetStatus = "no-change"
+	targetStatusFailed   targetStatus = "failed"
+	targetStatusSkipped  targetStatus = "skipped"
+)
+
+// targetResult is the result of a stage on a deploy target with the error when it failed.
+type targetResult struct {
+	Name   string
+	Status targetStatus
+	Err    error
+}
+
+// executionConfig returns how the stages run against multiple deploy targets.
+func executionConfig(spec *config.ApplicationConfigSpec) config.ExecutionConfig {
+	if spec == nil {
+		return config.ExecutionConfig{}
+	}
+	return spec.Execution
+}
+
+// runTargets runs fn on each deploy target with the concurrency and the error policy of the execution config,
+// and returns the results in the order of the given names.
+// Unless continuing on error, the deploy targets not started yet after a failure are skipped.
+// The deploy targets already started are not canceled since stopping sqldef halfway may leave the schema half changed.
+// When the deploy targets are processed at the same time, the logs of each of them are written at once after it finishes.
+func runTargets(lp sdk.StageLogPersister, exec config.ExecutionConfig, names []string, fn func(i int, lp sdk.StageLogPersister) (targetStatus, error)) []targetResult {
+	results := make([]targetResult, len(names))
+	for i, name := range names {
+		results[i] = targetResult{Name: name, Status: targetStatusSkipped}
+	}
+
+	concurrency := exec.ConcurrencyOrDefault()
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Bool
+		sem    = make(chan struct{}, concurrency)
+	)
+	for i := range names {
+		sem <- struct{}{}
+		if failed.Load() && !exec.ContinueOnError() {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			targetLP := lp
+			if concurrency > 1 {
+				buf := &bufferedLogPersister{name: names[i], stream: lp}
+				defer buf.flush(lp)
+				targetLP = buf
+			}
+
+			status, err := fn(i, targetLP)
+			if err != nil {
+				targetLP.Errorf("Deploy Target [%s]: %v", names[i], err)
+				status = targetStatusFailed
+				failed.Store(true)
+			}
+			results[i] = targetResult{Name: names[i], Status: status, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// reportTargetResults logs the status of each deploy target and returns true if none of them failed.
+func reportTargetResults(lp sdk.StageLogPersister, results []targetResult) bool {
+	counts := make(map[targetStatus]int)
+	lp.Info("Summary of the deploy targets:")
+	for _, r := range results {
+		counts[r.Status]++
+		if r.Status == targetStatusFailed {
+			lp.Errorf("  %s: %s (%v)", r.Name, r.Status, r.Err)
+			continue
+		}
+		lp.Infof("  %s: %s", r.Name, r.Status)
+	}
+
+	summary := "%d success, %d no-change, %d failed, %d skipped"
+	if counts[targetStatusFailed] > 0 {
+		lp.Errorf(summary, counts[targetStatusSuccess], counts[targetStatusNoChange], counts[targetStatusFailed], counts[targetStatusSkipped])
+		return false
+	}
+	lp.Infof(summary, counts[targetStatusSuccess], counts[targetStatusNoChange], counts[targetStatusFailed], counts[targetStatusSkipped])
+	return true
+}
+
+// targetNames returns the names of the deploy targets.
+func targetNames(dts []*sdk.DeployTarget[config.DeployTargetConfig]) []string {
+	names := make([]string, 0, len(dts))
+	for _, dt := range dts {
+		names = append(names, dt.Name)
+	}
+	return names
+}
+
+// bufferedLogPersister keeps the logs of a deploy target to write them at once
+// so that they are not interleaved with the logs of the other deploy targets processed at the same time.
+type bufferedLogPersister struct {
+	mu   sync.Mutex
+	logs []func(sdk.StageLogPersister)
+
+	// name and stream are used by streamLogPersister for the logs which can't wait for the deploy target to finish.
+	name   string
+	stream sdk.StageLogPersister
+}
+
+func (b *bufferedLogPersister) add(log func(sdk.StageLogPersister)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.logs = append(b.logs, log)
+}
+
+// flush writes the kept logs to the given log persister.
+func (b *bufferedLogPersister) flush(lp sdk.StageLogPersister) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, log := range b.logs {
+		log(lp)
+	}
+	b.logs = nil
+}
+
+func (b *bufferedLogPersister) Write(log []byte) (int, error) {
+	data := append([]byte(nil), log...)
+	b.add(func(lp sdk.StageLogPersister) { lp.Write(data) })
+	return len(log), nil
+}
+
+func (b *bufferedLogPersister) Info(log string) {
+	b.add(func(lp sdk.StageLogPersister) { lp.Info(log) })
+}
+
+func (b *bufferedLogPersister) Infof(format string, a ...any) {
+	b.Info(fmt.Sprintf(format, a...))
+}
+
+func (b *bufferedLogPersister) Success(log string) {
+	b.add(func(lp sdk.StageLogPersister) { lp.Success(log) })
+}
+
+func (b *bufferedLogPersister) Successf(format string, a ...any) {
+	b.Success(fmt.Sprintf(format, a...))
+}
+
+func (b *bufferedLogPersister) Error(log string) {
+	b.add(func(lp sdk.StageLogPersister) { lp.Error(log) })
+}
+
+func (b *bufferedLogPersister) Errorf(format string, a ...any) {
+	b.Error(fmt.Sprintf(format, a...))
+}
+
+// streamLogPersister returns the log persister writing the logs as they come,
+// for the long-running commands such as gh-ost whose progress can't wait for the deploy target to finish.
+// When the deploy target is processed with the others, each line is prefixed with its name instead.
+func streamLogPersister(lp sdk.StageLogPersister) *lineLogPersister {
+	if buf, ok := lp.(*bufferedLogPersister); ok && buf.stream != nil {
+		return &lineLogPersister{lp: buf.stream, prefix: fmt.Sprintf("[%s] ", buf.name)}
+	}
+	return &lineLogPersister{lp: lp}
+}
+
+// lineLogPersister writes the logs line by line with the prefix.
+// The last line written without a newline is kept until flush is called.
+type lineLogPersister struct {
+	mu      sync.Mutex
+	lp      sdk.StageLogPersister
+	prefix  string
+	partial []byte
+}
+
+func (l *lineLogPersister) Write(log []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.partial = append(l.partial, log...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.lp.Write(append([]byte(l.prefix), l.partial[:i+1]...))
+		l.partial = l.partial[i+1:]
+	}
+	return len(log), nil
+}
+
+// flush writes the last line kept without a newline.
+func (l *lineLogPersister) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.partial) > 0 {
+		l.lp.Write(append([]byte(l.prefix), l.partial...))
+		l.partial = nil
+	}
+}
+
+func (l *lineLogPersister) Info(log string) {
+	l.lp.Info(l.prefix + log)
+}
+
+func (l *lineLogPersister) Infof(format string, a ...any) {
+	l.Info(fmt.Sprintf(format, a...))
+}
+
+func (l *lineLogPersister) Success(log string) {
+	l.lp.Success(l.prefix + log)
+}
+
+func (l *lineLogPersister) Successf(format string, a ...any) {
+	l.Success(fmt.Sprintf(format, a...))
+}
+
+func (l *lineLogPersister) Error(log string) {
+	l.lp.Error(l.prefix + log)
+}
+
+func (l *lineLogPersister) Errorf(format string, a ...any) {
+	l.Error(fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,373 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+func TestRunTargets(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("failed")
+	names := []string{"shard-1", "shard-2", "shard-3"}
+	fn := func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		switch i {
+		case 0:
+			return targetStatusNoChange, nil
+		case 1:
+			return "", errFailed
+		default:
+			return targetStatusSuccess, nil
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		exec     config.ExecutionConfig
+		expected []targetResult
+	}{
+		{
+			name: "skips the remaining targets on failure",
+			exec: config.ExecutionConfig{},
+			expected: []targetResult{
+				{Name: "shard-1", Status: targetStatusNoChange},
+				{Name: "shard-2", Status: targetStatusFailed, Err: errFailed},
+				{Name: "shard-3", Status: targetStatusSkipped},
+			},
+		},
+		{
+			name: "continues on error",
+			exec: config.ExecutionConfig{OnError: config.OnErrorContinue},
+			expected: []targetResult{
+				{Name: "shard-1", Status: targetStatusNoChange},
+				{Name: "shard-2", Status: targetStatusFailed, Err: errFailed},
+				{Name: "shard-3", Status: targetStatusSuccess},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := runTargets(logpersistertest.NewTestLogPersister(t), tc.exec, names, fn)
+			assert.Equal(t, tc.expected, results)
+		})
+	}
+}
+
+func TestRunTargets_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	// Each target waits for all of them to start, so this finishes only when they run at the same time.
+	names := []string{"shard-1", "shard-2", "shard-3"}
+	var started sync.WaitGroup
+	started.Add(len(names))
+	fn := func(i int, lp sdk.StageLogPersister) (targetStatus, error) {
+		started.Done()
+		lp.Infof("Deploy Target [%s]: started", names[i])
+
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return targetStatusSuccess, nil
+		case <-time.After(10 * time.Second):
+			return "", errors.New("the other targets did not start")
+		}
+	}
+
+	results := runTargets(logpersistertest.NewTestLogPersister(t), config.ExecutionConfig{Concurrency: 3}, names, fn)
+	for _, r := range results {
+		assert.Equal(t, targetStatusSuccess, r.Status, r.Name)
+	}
+}
+
+func TestBufferedLogPersister(t *testing.T) {
+	t.Parallel()
+
+	var logs []string
+	buf := &bufferedLogPersister{}
+	buf.Infof("info %d", 1)
+	buf.Write([]byte("written"))
+	buf.Success("success")
+	buf.Errorf("error %d", 2)
+	assert.Empty(t, logs)
+
+	buf.flush(&recordingLogPersister{logs: &logs})
+	assert.Equal(t, []string{"INFO info 1", "WRITE written", "SUCCESS success", "ERROR error 2"}, logs)
+}
+
+// recordingLogPersister records the logs with their levels.
+type recordingLogPersister struct {
+	mu   sync.Mutex
+	logs *[]string
+}
+
+func (r *recordingLogPersister) record(log string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.logs = append(*r.logs, log)
+}
+
+func (r *recordingLogPersister) Write(log []byte) (int, error) {
+	r.record("WRITE " + string(log))
+	return len(log), nil
+}
+
+func (r *recordingLogPersister) Info(log string)    { r.record("INFO " + log) }
+func (r *recordingLogPersister) Success(log string) { r.record("SUCCESS " + log) }
+func (r *recordingLogPersister) Error(log string)   { r.record("ERROR " + log) }
+
+func (r *recordingLogPersister) Infof(format string, a ...any) { r.Info(fmt.Sprintf(format, a...)) }
+func (r *recordingLogPersister) Successf(format string, a ...any) {
+	r.Success(fmt.Sprintf(format, a...))
+}
+func (r *recordingLogPersister) Errorf(format string, a ...any) { r.Error(fmt.Sprintf(format, a...)) }
+
+func TestReportTargetResults(t *testing.T) {
+	t.Parallel()
+
+	lp := logpersistertest.NewTestLogPersister(t)
+	assert.True(t, reportTargetResults(lp, []targetResult{
+		{Name: "shard-1", Status: targetStatusSuccess},
+		{Name: "shard-2", Status: targetStatusNoChange},
+	}))
+	assert.False(t, reportTargetResults(lp, []targetResult{
+		{Name: "shard-1", Status: targetStatusSuccess},
+		{Name: "shard-2", Status: targetStatusFailed, Err: errors.New("failed")},
+		{Name: "shard-3", Status: targetStatusSkipped},
+	}))
+}
+
+func TestPlugin_executeApplyStage_ContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	schemaFiles := []string{filepath.Join("testdata", "app", "schema.sql")}
+	targetConfig1 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "shard1"}
+	targetConfig2 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "shard2"}
+
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, targetConfig1, schemaFiles, true).Return("", assert.AnError)
+	mockSqldef.On("Execute", ctx, targetConfig2, schemaFiles, true).Return(createUsersOutput, nil)
+	mockSqldef.On("ShowCurrentSchema", ctx, targetConfig2).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig2, schemaFiles, false).Return("", nil).Once()
+
+	ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+		ApplicationDirectory: filepath.Join("testdata", "app"),
+		CommitHash:           "0123456789",
+		ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+			Spec: &config.ApplicationConfigSpec{
+				Execution: config.ExecutionConfig{Concurrency: 2, OnError: config.OnErrorContinue},
+			},
+		},
+	}
+	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:               sqldefStageApply,
+			RunningDeploymentSource: ds,
+			TargetDeploymentSource:  ds,
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", logpersistertest.NewTestLogPersister(t), toolregistrytest.NewTestToolRegistry(t)),
+	}
+
+	store := newFakeMetadataStore()
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	status := plugin.executeApplyStage(ctx, store, []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "shard-1", Config: targetConfig1},
+		{Name: "shard-2", Config: targetConfig2},
+	}, input)
+
+	// The failure on shard-1 fails the stage after applying to shard-2,
+	// and only shard-2 is rolled back since only its schema is exported.
+	assert.Equal(t, sdk.StageStatusFailure, status)
+	mockSqldef.AssertExpectations(t)
+	_, found, _ := store.GetDeploymentPluginMetadata(ctx, preApplySchemaKey("shard-1"))
+	assert.False(t, found)
+	_, found, _ = store.GetDeploymentPluginMetadata(ctx, preApplySchemaKey("shard-2"))
+	assert.True(t, found)
+}
+
+// waitAllStarted returns a function blocking until it's called n times,
+// which fails the test when the calls aren't made concurrently.
+func waitAllStarted(t *testing.T, n int) func(mock.Arguments) {
+	var started sync.WaitGroup
+	started.Add(n)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	return func(mock.Arguments) {
+		started.Done()
+		select {
+		case <-allStarted:
+		case <-time.After(10 * time.Second):
+			t.Error("deploy targets aren't run concurrently")
+		}
+	}
+}
+
+// assertTargetLogsSeparated asserts that no log of another target is written
+// between the first and the last log containing one of the markers of a target.
+func assertTargetLogsSeparated(t *testing.T, logs []string, markers map[string][]string) {
+	t.Helper()
+	owner := func(log string) string {
+		for name, ms := range markers {
+			for _, m := range ms {
+				if strings.Contains(log, m) {
+					return name
+				}
+			}
+		}
+		return ""
+	}
+	for name := range markers {
+		first, last := -1, -1
+		for i, log := range logs {
+			if owner(log) == name {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if !assert.GreaterOrEqual(t, first, 0, "no logs for %s", name) {
+			continue
+		}
+		for _, log := range logs[first : last+1] {
+			if o := owner(log); o != "" && o != name {
+				assert.Failf(t, "interleaved logs", "log of %s between the logs of %s: %q", o, name, log)
+			}
+		}
+	}
+}
+
+// shardOutput returns the sqldef output creating a table named after the shard.
+func shardOutput(db string) string {
+	return fmt.Sprintf("CREATE TABLE %s_users (\n    id INT PRIMARY KEY\n);\n", db)
+}
+
+func TestPlugin_executePlanStage_ConcurrentTargets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	schemaFiles := []string{filepath.Join("testdata", "app", "schema.sql")}
+	targetConfig1 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "shard1"}
+	targetConfig2 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "shard2"}
+	targetConfig3 := config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "shard3"}
+
+	wait := waitAllStarted(t, 3)
+	mockSqldef := &MockSqldefProvider{}
+	mockSqldef.On("Execute", ctx, targetConfig1, schemaFiles, true).Run(wait).Return(shardOutput("shard1"), nil)
+	mockSqldef.On("Execute", ctx, targetConfig2, schemaFiles, true).Run(wait).Return("", nil)
+	mockSqldef.On("Execute", ctx, targetConfig3, schemaFiles, true).Run(wait).Return("", assert.AnError)
+
+	ds := sdk.DeploymentSource[config.ApplicationConfigSpec]{
+		ApplicationDirectory: filepath.Join("testdata", "app"),
+		CommitHash:           "0123456789",
+		ApplicationConfig: &sdk.ApplicationConfig[config.ApplicationConfigSpec]{
+			Spec: &config.ApplicationConfigSpec{
+				Execution: config.ExecutionConfig{Concurrency: 3, OnError: config.OnErrorContinue},
+			},
+		},
+	}
+	var logs []string
+	input := &sdk.ExecuteStageInput[config.ApplicationConfigSpec]{
+		Request: sdk.ExecuteStageRequest[config.ApplicationConfigSpec]{
+			StageName:               sqldefStagePlan,
+			RunningDeploymentSource: ds,
+			TargetDeploymentSource:  ds,
+		},
+		Client: sdk.NewClient(nil, "sqldef", "", "", &recordingLogPersister{logs: &logs}, toolregistrytest.NewTestToolRegistry(t)),
+	}
+
+	plugin := createPluginWithMockSqldef(mockSqldef)
+	status := plugin.executePlanStage(ctx, []*sdk.DeployTarget[config.DeployTargetConfig]{
+		{Name: "shard-1", Config: targetConfig1},
+		{Name: "shard-2", Config: targetConfig2},
+		{Name: "shard-3", Config: targetConfig3},
+	}, input)
+
+	assert.Equal(t, sdk.StageStatusFailure, status)
+	mockSqldef.AssertExpectations(t)
+	assert.Contains(t, logs, "INFO   shard-1: success")
+	assert.Contains(t, logs, "INFO   shard-2: no-change")
+	assert.Contains(t, logs, "ERROR   shard-3: failed (failed while planning the deployment: "+assert.AnError.Error()+")")
+	assertTargetLogsSeparated(t, logs, map[string][]string{
+		"shard-1": {"Deploy Target [shard-1]", "shard1"},
+		"shard-2": {"Deploy Target [shard-2]", "shard2"},
+		"shard-3": {"Deploy Target [shard-3]", "shard3"},
+	})
+}
+
+func TestStreamLogPersister(t *testing.T) {
+	t.Parallel()
+
+	t.Run("deploy target processed alone", func(t *testing.T) {
+		t.Parallel()
+
+		var logs []string
+		out := streamLogPersister(&recordingLogPersister{logs: &logs})
+		out.Info("altering")
+		out.Write([]byte("Copy: 10%\n"))
+		assert.Equal(t, []string{"INFO altering", "WRITE Copy: 10%\n"}, logs)
+	})
+
+	t.Run("deploy target processed with the others", func(t *testing.T) {
+		t.Parallel()
+
+		var logs []string
+		buf := &bufferedLogPersister{name: "shard-1", stream: &recordingLogPersister{logs: &logs}}
+		buf.Info("applying")
+		out := streamLogPersister(buf)
+		out.Info("altering")
+		out.Write([]byte("Copy: 10%\nCopy: "))
+		out.Write([]byte("20%\nCopy: 30%"))
+
+		// The progress is written as it comes while the other logs wait for the deploy target to finish.
+		assert.Equal(t, []string{
+			"INFO [shard-1] altering",
+			"WRITE [shard-1] Copy: 10%\n",
+			"WRITE [shard-1] Copy: 20%\n",
+		}, logs)
+
+		out.flush()
+		assert.Equal(t, "WRITE [shard-1] Copy: 30%", logs[len(logs)-1])
+		buf.flush(&recordingLogPersister{logs: &logs})
+		assert.Equal(t, "INFO applying", logs[len(logs)-1])
+	})
+}
@@ -1,29 +1,167 @@
 package deployment
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/provider"
 )
 
-func findFirstSQLFile(appDir string) (string, error) {
-	var firstSQLFile string
-	err := filepath.Walk(appDir, func(path string, info os.FileInfo, err error) error {
+// describeTarget returns the human readable location of the database of the given deploy target.
+func describeTarget(cfg config.DeployTargetConfig) string {
+	if cfg.DbType == config.DBTypeSQLite {
+		return fmt.Sprintf("path=%s", cfg.Path)
+	}
+	if cfg.Socket != "" {
+		return fmt.Sprintf("socket=%s, db=%s", cfg.Socket, cfg.DBName)
+	}
+	return fmt.Sprintf("host=%s, port=%s, db=%s", cfg.Host, cfg.Port, cfg.DBName)
+}
+
+// decodeStageOptions decodes the options of the stage, which are empty when the stage has no "with" field.
+func decodeStageOptions[T any](data []byte) (T, error) {
+	var opts T
+	if len(data) == 0 {
+		return opts, nil
+	}
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// reportChanges logs the number of statements for each kind found in the result of sqldef.
+func reportChanges(lp sdk.StageLogPersister, target string, result provider.ExecuteResult) {
+	if result.NoChanges() {
+		lp.Successf("Deploy Target [%s]: no changes", target)
+		return
+	}
+	counts := result.CountsByKind()
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	summary := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[kind], kind))
+	}
+	lp.Successf("Deploy Target [%s]: detected %d statements (%s)", target, len(result.Statements), strings.Join(summary, ", "))
+}
+
+// sqldefTarget returns the target to run sqldef against.
+// The tables and views filtered in the application config are applied to every sqldef command including the schema export
+// so that plan, apply and rollback manage the same objects.
+// The tracking table of the migration scripts is excluded since it is not defined in the schema files.
+func sqldefTarget(cfg config.DeployTargetConfig, execPath string, spec *config.ApplicationConfigSpec) provider.Target {
+	target := provider.Target{Config: cfg, ExecPath: execPath}
+	if spec == nil {
+		return target
+	}
+	target.TargetTables = spec.TargetTables
+	target.SkipTables = slices.Clone(spec.SkipTables)
+	target.SkipViews = spec.SkipViews
+	if spec.Migrations.Enabled() {
+		// psqldef qualifies the table names with the schema.
+		target.SkipTables = append(target.SkipTables, `(.*\.)?`+spec.Migrations.TableOrDefault())
+	}
+	return target
+}
+
+// applicationSpec returns the application config spec of the given deployment source, or nil if not set.
+func applicationSpec(ds sdk.DeploymentSource[config.ApplicationConfigSpec]) *config.ApplicationConfigSpec {
+	if ds.ApplicationConfig == nil {
+		return nil
+	}
+	return ds.ApplicationConfig.Spec
+}
+
+// resolveSchemaFiles returns the schema files of the application in the order to be concatenated.
+// When schemaFiles is not configured, the application directory must contain exactly one .sql file.
+func resolveSchemaFiles(appDir string, spec *config.ApplicationConfigSpec) ([]string, error) {
+	if spec == nil {
+		return findOnlySQLFile(appDir, "")
+	}
+	if len(spec.SchemaFiles) == 0 {
+		var migrationsDir string
+		if spec.Migrations.Enabled() {
+			migrationsDir = filepath.Join(appDir, spec.Migrations.Dir)
+		}
+		return findOnlySQLFile(appDir, migrationsDir)
+	}
+
+	var (
+		files []string
+		seen  = make(map[string]string)
+	)
+	for _, pattern := range spec.SchemaFiles {
+		if !filepath.IsLocal(pattern) {
+			return nil, fmt.Errorf("schemaFiles must be within the application directory: %s", pattern)
+		}
+		matches, err := filepath.Glob(filepath.Join(appDir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schemaFiles pattern %q: %w", pattern, err)
+		}
+		sort.Strings(matches)
+
+		var matched bool
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			if prev, ok := seen[m]; ok {
+				return nil, fmt.Errorf("schema file %s is matched by both %q and %q", m, prev, pattern)
+			}
+			seen[m] = pattern
+			files = append(files, m)
+			matched = true
+		}
+		if !matched {
+			return nil, fmt.Errorf("no schema file matches %q", pattern)
+		}
+	}
+	return files, nil
+}
+
+// findOnlySQLFile returns the only .sql file in the given directory and its subdirectories.
+// The migrations directory is skipped when given since its scripts are not part of the schema.
+func findOnlySQLFile(appDir, migrationsDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(appDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".sql") {
-			firstSQLFile = path
-			return filepath.SkipDir // Stop walking after finding the first .sql file
+		if d.IsDir() && migrationsDir != "" && filepath.Clean(path) == filepath.Clean(migrationsDir) {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".sql") {
+			files = append(files, path)
 		}
 		return nil
 	})
-	if err != nil && !errors.Is(err, filepath.SkipDir) {
-		return "", err
+	if err != nil {
+		return nil, err
 	}
-	if firstSQLFile == "" {
-		return "", os.ErrNotExist
+	switch len(files) {
+	case 0:
+		return nil, errors.New("no .sql file found in the application directory")
+	case 1:
+		return files, nil
+	default:
+		return nil, fmt.Errorf("found multiple .sql files (%s), specify the ones to deploy with schemaFiles", strings.Join(files, ", "))
 	}
-	return firstSQLFile, nil
 }
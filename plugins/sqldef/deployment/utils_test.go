@@ -0,0 +1,162 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("-- "+f+"\n"), 0644))
+	}
+}
+
+func TestResolveSchemaFiles(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		files    []string
+		spec     *config.ApplicationConfigSpec
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "only one sql file without schemaFiles",
+			files:    []string{"schema/schema.sql", "README.md"},
+			expected: []string{"schema/schema.sql"},
+		},
+		{
+			name:    "multiple sql files without schemaFiles",
+			files:   []string{"schema.sql", "seed/seed.sql"},
+			spec:    &config.ApplicationConfigSpec{},
+			wantErr: true,
+		},
+		{
+			name:  "migrations are not schema files",
+			files: []string{"schema.sql", "migrations/1_backfill_users.sql", "migrations/2_backfill_posts.sql"},
+			spec: &config.ApplicationConfigSpec{
+				Migrations: config.MigrationsConfig{Dir: "migrations"},
+			},
+			expected: []string{"schema.sql"},
+		},
+		{
+			name:    "no sql file without schemaFiles",
+			files:   []string{"README.md"},
+			wantErr: true,
+		},
+		{
+			name:  "files in the given order",
+			files: []string{"tables.sql", "views.sql", "seed.sql"},
+			spec: &config.ApplicationConfigSpec{
+				SchemaFiles: []string{"views.sql", "tables.sql"},
+			},
+			expected: []string{"views.sql", "tables.sql"},
+		},
+		{
+			name:  "glob patterns are sorted",
+			files: []string{"schema/02_views.sql", "schema/01_tables.sql", "seed/seed.sql"},
+			spec: &config.ApplicationConfigSpec{
+				SchemaFiles: []string{"schema/*.sql"},
+			},
+			expected: []string{"schema/01_tables.sql", "schema/02_views.sql"},
+		},
+		{
+			name:  "pattern matching nothing",
+			files: []string{"schema.sql"},
+			spec: &config.ApplicationConfigSpec{
+				SchemaFiles: []string{"schema.sql", "tables/*.sql"},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "file outside the application directory",
+			files: []string{"schema.sql"},
+			spec: &config.ApplicationConfigSpec{
+				SchemaFiles: []string{"../schema.sql"},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "file matched twice",
+			files: []string{"schema/tables.sql"},
+			spec: &config.ApplicationConfigSpec{
+				SchemaFiles: []string{"schema/tables.sql", "schema/*.sql"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files...)
+
+			got, err := resolveSchemaFiles(dir, tc.spec)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expected := make([]string, 0, len(tc.expected))
+			for _, f := range tc.expected {
+				expected = append(expected, filepath.Join(dir, f))
+			}
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestSqldefTarget(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DeployTargetConfig{DbType: config.DBTypeSQLite, Path: "/tmp/test.db"}
+
+	target := sqldefTarget(cfg, "/bin/sqlite3def", nil)
+	assert.Empty(t, target.SkipTables)
+
+	target = sqldefTarget(cfg, "/bin/sqlite3def", &config.ApplicationConfigSpec{
+		Migrations: config.MigrationsConfig{Dir: "migrations"},
+	})
+	assert.Equal(t, "/bin/sqlite3def", target.ExecPath)
+	assert.Equal(t, []string{`(.*\.)?sqldef_migrations`}, target.SkipTables)
+
+	spec := &config.ApplicationConfigSpec{
+		TargetTables: []string{"users", "posts"},
+		SkipTables:   []string{"schema_migrations"},
+		SkipViews:    true,
+		Migrations:   config.MigrationsConfig{Dir: "migrations"},
+	}
+	target = sqldefTarget(cfg, "/bin/sqlite3def", spec)
+	assert.Equal(t, []string{"users", "posts"}, target.TargetTables)
+	assert.Equal(t, []string{"schema_migrations", `(.*\.)?sqldef_migrations`}, target.SkipTables)
+	assert.True(t, target.SkipViews)
+	// The skip tables of the application config are not modified.
+	assert.Equal(t, []string{"schema_migrations"}, spec.SkipTables)
+}
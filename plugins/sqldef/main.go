@@ -22,11 +22,16 @@ import (
 )
 
 func main() {
+	p := &deployment.Plugin{
+		Sqldef:             &provider.SqldefProviderImpl{},
+		Migrator:           &provider.MigrationProviderImpl{},
+		OnlineSchemaChange: &provider.OnlineSchemaChangeProviderImpl{},
+	}
 	plugin, err := sdk.NewPlugin(
 		"0.0.1",
-		sdk.WithDeploymentPlugin(&deployment.Plugin{
-			Sqldef: &provider.SqldefProviderImpl{},
-		}),
+		sdk.WithDeploymentPlugin(p),
+		sdk.WithPlanPreviewPlugin(p),
+		sdk.WithLivestatePlugin(p),
 	)
 	if err != nil {
 		log.Fatalln(err)
@@ -0,0 +1,164 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// appendFlag appends the flag and its value to args only when the value is set.
+func appendFlag(args []string, flag, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, flag, value)
+}
+
+// appendEnv appends the environment variable only when the value is set.
+func appendEnv(envs []string, name, value string) []string {
+	if value == "" {
+		return envs
+	}
+	return append(envs, name+"="+value)
+}
+
+// connectionArgs returns the flags to connect to the database of the given deploy target.
+// Each sqldef command has its own flags, e.g. mysqldef uses "-P" for the port while psqldef uses "-p".
+// The password is not included since process arguments are visible to other users on the host,
+// it is passed with the environment variable instead. See connectionEnvs.
+func connectionArgs(dt config.DeployTargetConfig) ([]string, error) {
+	var args []string
+	switch dt.DbType {
+	case config.DBTypeMySQL:
+		args = appendFlag(args, "-u", dt.Username)
+		args = appendFlag(args, "-h", dt.Host)
+		args = appendFlag(args, "-P", dt.Port)
+		args = appendFlag(args, "-S", dt.Socket)
+		args = appendFlag(args, "--ssl-mode", strings.ToUpper(dt.SSLMode))
+		args = appendFlag(args, "--ssl-ca", dt.SSLCA)
+	case config.DBTypePostgres:
+		args = appendFlag(args, "-U", dt.Username)
+		// psqldef connects with the Unix socket in the directory given as the host.
+		args = appendFlag(args, "-h", cmp.Or(dt.Host, dt.Socket))
+		args = appendFlag(args, "-p", dt.Port)
+	case config.DBTypeMSSQL:
+		// mssqldef has no flags for the instance and the encryption, they are only used by the migrations.
+		args = appendFlag(args, "-U", dt.Username)
+		args = appendFlag(args, "-h", dt.Host)
+		args = appendFlag(args, "-p", dt.Port)
+	case config.DBTypeSQLite:
+		// sqlite3def connects to the database file given as the positional argument.
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dt.DbType)
+	}
+	return args, nil
+}
+
+// databaseArg returns the positional argument of sqldef commands to specify the database.
+func databaseArg(dt config.DeployTargetConfig) string {
+	if dt.DbType == config.DBTypeSQLite {
+		return dt.Path
+	}
+	return dt.DBName
+}
+
+// passwordEnvs is the environment variable each sqldef command reads the password from.
+var passwordEnvs = map[config.DBType]string{
+	config.DBTypeMySQL:    "MYSQL_PWD",
+	config.DBTypePostgres: "PGPASSWORD",
+	config.DBTypeMSSQL:    "MSSQL_PWD",
+}
+
+// connectionEnvs returns the environment variables to connect to the database of the given deploy target.
+func connectionEnvs(dt config.DeployTargetConfig, password string) []string {
+	var envs []string
+	if name, ok := passwordEnvs[dt.DbType]; ok && password != "" {
+		envs = append(envs, name+"="+password)
+	}
+	if dt.DbType == config.DBTypePostgres {
+		// psqldef reads the TLS options from the environment variables of libpq.
+		envs = appendEnv(envs, "PGSSLMODE", strings.ToLower(dt.SSLMode))
+		envs = appendEnv(envs, "PGSSLROOTCERT", dt.SSLCA)
+		envs = appendEnv(envs, "PGSSLCERT", dt.SSLCert)
+		envs = appendEnv(envs, "PGSSLKEY", dt.SSLKey)
+	}
+	return envs
+}
+
+// redact replaces the secret in the given text so that it can be logged.
+func redact(text, secret string) string {
+	if secret == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, secret, "******")
+}
+
+// renderConfigFile renders the content of the sqldef config file passed with "--config" flag.
+// It returns an empty string when no config is needed.
+func renderConfigFile(target Target) string {
+	var b strings.Builder
+	if target.Config.DbType == config.DBTypePostgres && target.Config.Schema != "" {
+		fmt.Fprintf(&b, "target_schema: %q\n", target.Config.Schema)
+	}
+	// sqldef reads the tables separated by newlines.
+	writeTablesBlock(&b, "target_tables", target.TargetTables)
+	writeTablesBlock(&b, "skip_tables", target.SkipTables)
+	if target.SkipViews {
+		b.WriteString("skip_views: true\n")
+	}
+	return b.String()
+}
+
+// writeTablesBlock writes the table patterns as a block scalar with one pattern per line.
+func writeTablesBlock(b *strings.Builder, key string, tables []string) {
+	if len(tables) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s: |\n", key)
+	for _, t := range tables {
+		fmt.Fprintf(b, "  %s\n", t)
+	}
+}
+
+// configFileArgs writes the sqldef config file for the given target if needed and returns the flag to use it.
+// The returned cleanup function removes the written file.
+func configFileArgs(target Target) ([]string, func(), error) {
+	content := renderConfigFile(target)
+	if content == "" {
+		return nil, func() {}, nil
+	}
+
+	f, err := os.CreateTemp("", "sqldef-config-*.yaml")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sqldef config file: %w", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write sqldef config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write sqldef config file: %w", err)
+	}
+	return []string{"--config", f.Name()}, cleanup, nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+func TestConnectionArgs(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		dt       config.DeployTargetConfig
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "mysql",
+			dt: config.DeployTargetConfig{
+				DbType:   config.DBTypeMySQL,
+				Username: "user",
+				Password: "pass",
+				Host:     "localhost",
+				Port:     "3306",
+				DBName:   "db",
+			},
+			expected: []string{"-u", "user", "-h", "localhost", "-P", "3306"},
+		},
+		{
+			name: "postgres",
+			dt: config.DeployTargetConfig{
+				DbType:   config.DBTypePostgres,
+				Username: "user",
+				Password: "pass",
+				Host:     "localhost",
+				Port:     "5432",
+				DBName:   "db",
+			},
+			expected: []string{"-U", "user", "-h", "localhost", "-p", "5432"},
+		},
+		{
+			name: "mssql",
+			dt: config.DeployTargetConfig{
+				DbType:   config.DBTypeMSSQL,
+				Username: "sa",
+				Password: "pass",
+				Host:     "localhost",
+				Port:     "1433",
+				DBName:   "db",
+			},
+			expected: []string{"-U", "sa", "-h", "localhost", "-p", "1433"},
+		},
+		{
+			name: "mysql with socket and tls",
+			dt: config.DeployTargetConfig{
+				DbType:   config.DBTypeMySQL,
+				Username: "user",
+				Socket:   "/var/run/mysqld/mysqld.sock",
+				SSLMode:  "required",
+				SSLCA:    "/etc/ssl/ca.pem",
+				DBName:   "db",
+			},
+			expected: []string{"-u", "user", "-S", "/var/run/mysqld/mysqld.sock", "--ssl-mode", "REQUIRED", "--ssl-ca", "/etc/ssl/ca.pem"},
+		},
+		{
+			name: "mssql passes only the flags mssqldef supports",
+			dt: config.DeployTargetConfig{
+				DbType:                 config.DBTypeMSSQL,
+				Username:               "sa",
+				Host:                   "db.example.com",
+				Port:                   "1434",
+				Instance:               "SQLEXPRESS",
+				Encrypt:                "Strict",
+				TrustServerCertificate: true,
+				DBName:                 "db",
+			},
+			expected: []string{"-U", "sa", "-h", "db.example.com", "-p", "1434"},
+		},
+		{
+			name: "postgres with socket",
+			dt: config.DeployTargetConfig{
+				DbType:   config.DBTypePostgres,
+				Username: "user",
+				Socket:   "/var/run/postgresql",
+				DBName:   "db",
+			},
+			expected: []string{"-U", "user", "-h", "/var/run/postgresql"},
+		},
+		{
+			name: "empty values are skipped",
+			dt: config.DeployTargetConfig{
+				DbType:   config.DBTypePostgres,
+				Username: "user",
+				DBName:   "db",
+			},
+			expected: []string{"-U", "user"},
+		},
+		{
+			name: "sqlite uses no connection flags",
+			dt: config.DeployTargetConfig{
+				DbType: config.DBTypeSQLite,
+				Path:   "/tmp/test.db",
+			},
+		},
+		{
+			name: "unsupported db type",
+			dt: config.DeployTargetConfig{
+				DbType: config.DBType("oracle"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			args, err := connectionArgs(tc.dt)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, args)
+		})
+	}
+}
+
+func TestDatabaseArg(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "db", databaseArg(config.DeployTargetConfig{DbType: config.DBTypeMySQL, DBName: "db"}))
+	assert.Equal(t, "/tmp/test.db", databaseArg(config.DeployTargetConfig{DbType: config.DBTypeSQLite, DBName: "db", Path: "/tmp/test.db"}))
+}
+
+func TestConnectionEnvs(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		dt       config.DeployTargetConfig
+		password string
+		expected []string
+	}{
+		{
+			name:     "mysql with password",
+			dt:       config.DeployTargetConfig{DbType: config.DBTypeMySQL},
+			password: "pass",
+			expected: []string{"MYSQL_PWD=pass"},
+		},
+		{
+			name:     "postgres with password and sslmode",
+			dt:       config.DeployTargetConfig{DbType: config.DBTypePostgres, SSLMode: "verify-full"},
+			password: "pass",
+			expected: []string{"PGPASSWORD=pass", "PGSSLMODE=verify-full"},
+		},
+		{
+			name: "postgres with tls files",
+			dt: config.DeployTargetConfig{
+				DbType:  config.DBTypePostgres,
+				SSLMode: "verify-full",
+				SSLCA:   "/etc/ssl/ca.pem",
+				SSLCert: "/etc/ssl/client.pem",
+				SSLKey:  "/etc/ssl/client.key",
+			},
+			expected: []string{"PGSSLMODE=verify-full", "PGSSLROOTCERT=/etc/ssl/ca.pem", "PGSSLCERT=/etc/ssl/client.pem", "PGSSLKEY=/etc/ssl/client.key"},
+		},
+		{
+			name:     "mssql with password",
+			dt:       config.DeployTargetConfig{DbType: config.DBTypeMSSQL},
+			password: "pass",
+			expected: []string{"MSSQL_PWD=pass"},
+		},
+		{
+			name: "postgres without password nor sslmode",
+			dt:   config.DeployTargetConfig{DbType: config.DBTypePostgres},
+		},
+		{
+			name:     "sqlite ignores password",
+			dt:       config.DeployTargetConfig{DbType: config.DBTypeSQLite},
+			password: "pass",
+		},
+		{
+			name: "sslmode is ignored for mysql",
+			dt:   config.DeployTargetConfig{DbType: config.DBTypeMySQL, SSLMode: "require"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, connectionEnvs(tc.dt, tc.password))
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Access denied for user 'root' (using password: ******)", redact("Access denied for user 'root' (using password: s3cret)", "s3cret"))
+	assert.Equal(t, "no secret", redact("no secret", ""))
+}
+
+func TestConfigFileArgs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("postgres with schema", func(t *testing.T) {
+		t.Parallel()
+		args, cleanup, err := configFileArgs(Target{Config: config.DeployTargetConfig{
+			DbType: config.DBTypePostgres,
+			Schema: "app",
+		}})
+		require.NoError(t, err)
+		require.Len(t, args, 2)
+		assert.Equal(t, "--config", args[0])
+
+		data, err := os.ReadFile(args[1])
+		require.NoError(t, err)
+		assert.Equal(t, "target_schema: \"app\"\n", string(data))
+
+		cleanup()
+		_, err = os.Stat(args[1])
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("skip tables", func(t *testing.T) {
+		t.Parallel()
+		args, cleanup, err := configFileArgs(Target{
+			Config: config.DeployTargetConfig{
+				DbType: config.DBTypePostgres,
+				Schema: "app",
+			},
+			SkipTables: []string{`(.*\.)?sqldef_migrations`},
+		})
+		require.NoError(t, err)
+		defer cleanup()
+		require.Len(t, args, 2)
+
+		data, err := os.ReadFile(args[1])
+		require.NoError(t, err)
+		assert.Equal(t, "target_schema: \"app\"\nskip_tables: |\n  (.*\\.)?sqldef_migrations\n", string(data))
+	})
+
+	t.Run("managed objects", func(t *testing.T) {
+		t.Parallel()
+		args, cleanup, err := configFileArgs(Target{
+			Config:       config.DeployTargetConfig{DbType: config.DBTypeMySQL},
+			TargetTables: []string{"users", "posts_.*"},
+			SkipTables:   []string{"schema_migrations", "sqldef_migrations"},
+			SkipViews:    true,
+		})
+		require.NoError(t, err)
+		defer cleanup()
+		require.Len(t, args, 2)
+
+		data, err := os.ReadFile(args[1])
+		require.NoError(t, err)
+		assert.Equal(t, "target_tables: |\n  users\n  posts_.*\nskip_tables: |\n  schema_migrations\n  sqldef_migrations\nskip_views: true\n", string(data))
+	})
+
+	t.Run("no config needed", func(t *testing.T) {
+		t.Parallel()
+		args, cleanup, err := configFileArgs(Target{Config: config.DeployTargetConfig{
+			DbType: config.DBTypeMySQL,
+			Schema: "app",
+		}})
+		require.NoError(t, err)
+		assert.Empty(t, args)
+		cleanup()
+	})
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// OnlineSchemaChangeProvider runs ALTER TABLE on MySQL with gh-ost,
+// which copies the table in the background instead of locking it.
+type OnlineSchemaChangeProvider interface {
+	// TableSizes returns the size of each table in the database of the deploy target in bytes.
+	TableSizes(ctx context.Context, cfg config.DeployTargetConfig) (map[string]int64, error)
+	// Alter runs the ALTER TABLE clauses against the table with gh-ost at target.ExecPath.
+	// The output of gh-ost including its progress is streamed to the logger.
+	Alter(ctx context.Context, logger sdk.StageLogPersister, target Target, table, alter string, extraArgs []string) error
+}
+
+type OnlineSchemaChangeProviderImpl struct{}
+
+func (p *OnlineSchemaChangeProviderImpl) TableSizes(ctx context.Context, cfg config.DeployTargetConfig) (map[string]int64, error) {
+	if cfg.DbType != config.DBTypeMySQL {
+		return nil, fmt.Errorf("online schema change is not supported for %s", cfg.DbType)
+	}
+
+	db, _, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT table_name, data_length + index_length FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the table sizes: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var (
+			name string
+			size int64
+		)
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, fmt.Errorf("failed to get the table sizes: %w", err)
+		}
+		sizes[name] = size
+	}
+	return sizes, rows.Err()
+}
+
+func (p *OnlineSchemaChangeProviderImpl) Alter(ctx context.Context, logger sdk.StageLogPersister, target Target, table, alter string, extraArgs []string) error {
+	args, err := ghostArgs(target.Config, table, alter)
+	if err != nil {
+		return err
+	}
+
+	password, err := target.Config.LoadPassword()
+	if err != nil {
+		return err
+	}
+	confArgs, cleanup, err := ghostConfFileArgs(target.Config, password)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	args = append(confArgs, args...)
+	args = append(args, extraArgs...)
+
+	cmd := exec.CommandContext(ctx, target.ExecPath, args...)
+	cmd.Stdout = logger
+	cmd.Stderr = logger
+
+	logger.Infof("gh-ost %s", strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to alter %s with gh-ost: %w", table, err)
+	}
+	return nil
+}
+
+// ghostArgs returns the flags of gh-ost to alter the table of the given deploy target.
+// The credentials are passed with the config file instead. See ghostConfFileArgs.
+func ghostArgs(cfg config.DeployTargetConfig, table, alter string) ([]string, error) {
+	if cfg.DbType != config.DBTypeMySQL {
+		return nil, fmt.Errorf("online schema change is not supported for %s", cfg.DbType)
+	}
+	if cfg.Socket != "" {
+		return nil, fmt.Errorf("gh-ost does not support connecting with a socket")
+	}
+
+	var args []string
+	args = appendGhostFlag(args, "host", cfg.Host)
+	args = appendGhostFlag(args, "port", cfg.Port)
+	args = appendGhostFlag(args, "database", cfg.DBName)
+	args = appendGhostFlag(args, "table", table)
+	args = appendGhostFlag(args, "alter", alter)
+	switch {
+	case cfg.SSLCA != "":
+		args = append(args, "--ssl")
+		args = appendGhostFlag(args, "ssl-ca", cfg.SSLCA)
+	case cfg.SSLMode == "required":
+		args = append(args, "--ssl", "--ssl-allow-insecure")
+	}
+	return append(args, "--execute"), nil
+}
+
+// appendGhostFlag appends the flag in the form of "--name=value" only when the value is set.
+func appendGhostFlag(args []string, name, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, fmt.Sprintf("--%s=%s", name, value))
+}
+
+// ghostConfFileArgs writes the credentials to the config file read by gh-ost and returns the flag to use it.
+// The returned cleanup function removes the written file.
+func ghostConfFileArgs(cfg config.DeployTargetConfig, password string) ([]string, func(), error) {
+	f, err := os.CreateTemp("", "gh-ost-*.cnf")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create gh-ost config file: %w", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	content := fmt.Sprintf("[client]\nuser=%s\npassword=%s\n", cfg.Username, password)
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write gh-ost config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write gh-ost config file: %w", err)
+	}
+	return []string{"--conf=" + f.Name()}, cleanup, nil
+}
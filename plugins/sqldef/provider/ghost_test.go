@@ -0,0 +1,137 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+func TestGhostArgs(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		cfg      config.DeployTargetConfig
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "tcp",
+			cfg: config.DeployTargetConfig{
+				DbType: config.DBTypeMySQL,
+				Host:   "db.example.com",
+				Port:   "3306",
+				DBName: "app",
+			},
+			expected: []string{"--host=db.example.com", "--port=3306", "--database=app", "--table=events", "--alter=ADD COLUMN `v` INT", "--execute"},
+		},
+		{
+			name: "tls with ca",
+			cfg: config.DeployTargetConfig{
+				DbType:  config.DBTypeMySQL,
+				Host:    "db.example.com",
+				DBName:  "app",
+				SSLMode: "required",
+				SSLCA:   "/etc/ca.pem",
+			},
+			expected: []string{"--host=db.example.com", "--database=app", "--table=events", "--alter=ADD COLUMN `v` INT", "--ssl", "--ssl-ca=/etc/ca.pem", "--execute"},
+		},
+		{
+			name: "tls without ca",
+			cfg: config.DeployTargetConfig{
+				DbType:  config.DBTypeMySQL,
+				Host:    "db.example.com",
+				DBName:  "app",
+				SSLMode: "required",
+			},
+			expected: []string{"--host=db.example.com", "--database=app", "--table=events", "--alter=ADD COLUMN `v` INT", "--ssl", "--ssl-allow-insecure", "--execute"},
+		},
+		{
+			name: "socket",
+			cfg: config.DeployTargetConfig{
+				DbType: config.DBTypeMySQL,
+				Socket: "/var/run/mysqld/mysqld.sock",
+				DBName: "app",
+			},
+			wantErr: true,
+		},
+		{
+			name: "postgres",
+			cfg: config.DeployTargetConfig{
+				DbType: config.DBTypePostgres,
+				DBName: "app",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			args, err := ghostArgs(tc.cfg, "events", "ADD COLUMN `v` INT")
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, args)
+		})
+	}
+}
+
+func TestOnlineSchemaChangeProviderImpl_Alter(t *testing.T) {
+	t.Parallel()
+
+	// The fake gh-ost succeeds only when it gets the credentials from the config file
+	// and the password is not in the arguments.
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "gh-ost")
+	script := `#!/bin/sh
+case "$*" in *s3cret*) echo "password in args" >&2; exit 1;; esac
+conf=$(echo "$1" | sed 's/^--conf=//')
+grep -q '^user=root$' "$conf" && grep -q '^password=s3cret$' "$conf" || exit 1
+echo "Copy: 100/100 100.0%"
+`
+	require.NoError(t, os.WriteFile(execPath, []byte(script), 0755))
+
+	target := Target{
+		Config: config.DeployTargetConfig{
+			DbType:   config.DBTypeMySQL,
+			Username: "root",
+			Password: "s3cret",
+			Host:     "localhost",
+			DBName:   "app",
+		},
+		ExecPath: execPath,
+	}
+
+	p := &OnlineSchemaChangeProviderImpl{}
+	err := p.Alter(t.Context(), logpersistertest.NewTestLogPersister(t), target, "events", "ADD COLUMN `v` INT", []string{"--allow-on-master"})
+	require.NoError(t, err)
+
+	// The config file is removed after running gh-ost.
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), "gh-ost-*.cnf"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
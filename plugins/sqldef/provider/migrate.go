@@ -0,0 +1,313 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/microsoft/go-mssqldb"
+	_ "modernc.org/sqlite"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+// Migration is a versioned SQL script run once against each deploy target.
+type Migration struct {
+	// Version orders the scripts and identifies the script in the tracking table.
+	Version int64
+	// Name is the file name of the script.
+	Name string
+	// Script is the content of the script.
+	Script string
+	// Checksum is the SHA-256 checksum of the script in hex.
+	Checksum string
+}
+
+// AppliedMigration is a script recorded in the tracking table.
+type AppliedMigration struct {
+	Version  int64
+	Name     string
+	Checksum string
+}
+
+// MigrationProvider runs versioned SQL scripts and records them in the tracking table of the deploy target.
+// Unlike SqldefProvider, it connects to the database directly since sqldef only handles DDL.
+type MigrationProvider interface {
+	// AppliedMigrations returns the scripts recorded in the tracking table ordered by their versions.
+	// It returns no scripts when the table does not exist yet.
+	AppliedMigrations(ctx context.Context, cfg config.DeployTargetConfig, table string) ([]AppliedMigration, error)
+	// ApplyMigration runs the script and records it in the tracking table in a transaction.
+	// The tracking table is created if it does not exist.
+	// For MySQL, DDL in the script is committed implicitly and is not rolled back with the record.
+	ApplyMigration(ctx context.Context, cfg config.DeployTargetConfig, table string, m Migration) error
+}
+
+type MigrationProviderImpl struct{}
+
+// dialect is the SQL to manage the tracking table on each database.
+type dialect struct {
+	driver string
+	// createTable creates the tracking table given as the first argument if it does not exist.
+	createTable string
+	// countTables counts the tables in the current schema named as the first parameter.
+	countTables string
+	// placeholder returns the placeholder of the n-th parameter.
+	placeholder func(n int) string
+}
+
+func questionPlaceholder(int) string { return "?" }
+
+var dialects = map[config.DBType]dialect{
+	config.DBTypeMySQL: {
+		driver:      "mysql",
+		createTable: "CREATE TABLE IF NOT EXISTS %[1]s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		countTables: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		placeholder: questionPlaceholder,
+	},
+	config.DBTypePostgres: {
+		driver:      "pgx",
+		createTable: "CREATE TABLE IF NOT EXISTS %[1]s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		countTables: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1",
+		placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	},
+	config.DBTypeSQLite: {
+		driver:      "sqlite",
+		createTable: "CREATE TABLE IF NOT EXISTS %[1]s (version INTEGER NOT NULL PRIMARY KEY, name TEXT NOT NULL, checksum TEXT NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		countTables: "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		placeholder: questionPlaceholder,
+	},
+	config.DBTypeMSSQL: {
+		driver:      "sqlserver",
+		createTable: "IF OBJECT_ID(N'%[1]s', N'U') IS NULL CREATE TABLE %[1]s (version BIGINT NOT NULL PRIMARY KEY, name NVARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, applied_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME())",
+		countTables: "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = SCHEMA_NAME() AND TABLE_NAME = @p1",
+		placeholder: func(n int) string { return fmt.Sprintf("@p%d", n) },
+	},
+}
+
+func (p *MigrationProviderImpl) AppliedMigrations(ctx context.Context, cfg config.DeployTargetConfig, table string) ([]AppliedMigration, error) {
+	db, d, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var n int
+	if err := db.QueryRowContext(ctx, d.countTables, table).Scan(&n); err != nil {
+		return nil, fmt.Errorf("failed to check the migrations table: %w", err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version, name, checksum FROM %s ORDER BY version", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to list the applied migrations: %w", err)
+		}
+		applied = append(applied, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list the applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func (p *MigrationProviderImpl) ApplyMigration(ctx context.Context, cfg config.DeployTargetConfig, table string, m Migration) error {
+	db, d, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(d.createTable, table)); err != nil {
+		return fmt.Errorf("failed to create the migrations table: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// MySQL commits DDL in the script implicitly, so only DML is rolled back on failure there.
+	if _, err := tx.ExecContext(ctx, m.Script); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to run %s: %w", m.Name, err)
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES (%s, %s, %s)", table, d.placeholder(1), d.placeholder(2), d.placeholder(3))
+	if _, err := tx.ExecContext(ctx, insert, m.Version, m.Name, m.Checksum); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record %s: %w", m.Name, err)
+	}
+	return tx.Commit()
+}
+
+// openDB opens the database of the given deploy target.
+func openDB(cfg config.DeployTargetConfig) (*sql.DB, dialect, error) {
+	d, ok := dialects[cfg.DbType]
+	if !ok {
+		return nil, dialect{}, fmt.Errorf("unsupported database type: %s", cfg.DbType)
+	}
+
+	password, err := cfg.LoadPassword()
+	if err != nil {
+		return nil, dialect{}, err
+	}
+
+	if cfg.DbType == config.DBTypeMySQL {
+		mc, err := mysqlConfig(cfg, password)
+		if err != nil {
+			return nil, dialect{}, err
+		}
+		connector, err := mysql.NewConnector(mc)
+		if err != nil {
+			return nil, dialect{}, err
+		}
+		return sql.OpenDB(connector), d, nil
+	}
+
+	var dsn string
+	switch cfg.DbType {
+	case config.DBTypePostgres:
+		dsn = postgresDSN(cfg, password)
+	case config.DBTypeMSSQL:
+		dsn = mssqlDSN(cfg, password)
+	case config.DBTypeSQLite:
+		dsn = cfg.Path
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, dialect{}, err
+	}
+	return db, d, nil
+}
+
+// mysqlConfig returns the config of the MySQL driver, which runs multiple statements in a script at once.
+func mysqlConfig(cfg config.DeployTargetConfig, password string) (*mysql.Config, error) {
+	mc := mysql.NewConfig()
+	mc.User = cfg.Username
+	mc.Passwd = password
+	mc.DBName = cfg.DBName
+	mc.MultiStatements = true
+	if cfg.Socket != "" {
+		mc.Net = "unix"
+		mc.Addr = cfg.Socket
+	} else {
+		mc.Net = "tcp"
+		mc.Addr = net.JoinHostPort(cmp.Or(cfg.Host, "127.0.0.1"), cmp.Or(cfg.Port, "3306"))
+	}
+
+	// Like the --ssl-mode of mysqldef, "required" encrypts the connection without verifying the server certificate.
+	// The certificate is verified only when ssl_ca is given.
+	mode := strings.ToLower(cfg.SSLMode)
+	switch mode {
+	case "required":
+		mc.TLSConfig = "skip-verify"
+	case "preferred":
+		mc.TLSConfig = "preferred"
+	case "disabled":
+		mc.TLSConfig = "false"
+	}
+	if cfg.SSLCA != "" && mode != "disabled" {
+		pem, err := os.ReadFile(cfg.SSLCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssl_ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in ssl_ca: %s", cfg.SSLCA)
+		}
+		mc.TLSConfig = ""
+		mc.TLS = &tls.Config{RootCAs: pool, ServerName: cfg.Host}
+	}
+	return mc, nil
+}
+
+// postgresDSN returns the connection URL of PostgreSQL.
+func postgresDSN(cfg config.DeployTargetConfig, password string) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.Username, password),
+		Path:   "/" + cfg.DBName,
+	}
+	if password == "" {
+		u.User = url.User(cfg.Username)
+	}
+
+	q := url.Values{}
+	if cfg.Socket != "" {
+		q.Set("host", cfg.Socket)
+	} else {
+		u.Host = cmp.Or(cfg.Host, "localhost")
+	}
+	if cfg.Port != "" {
+		q.Set("port", cfg.Port)
+	}
+	setValue(q, "sslmode", strings.ToLower(cfg.SSLMode))
+	setValue(q, "sslrootcert", cfg.SSLCA)
+	setValue(q, "sslcert", cfg.SSLCert)
+	setValue(q, "sslkey", cfg.SSLKey)
+	setValue(q, "search_path", cfg.Schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// mssqlDSN returns the connection URL of SQL Server.
+// The named instance is given as the path, while the port takes precedence over it to connect.
+func mssqlDSN(cfg config.DeployTargetConfig, password string) string {
+	host := cmp.Or(cfg.Host, "localhost")
+	if cfg.Port != "" {
+		host = net.JoinHostPort(host, cfg.Port)
+	}
+	q := url.Values{}
+	q.Set("database", cfg.DBName)
+	setValue(q, "encrypt", strings.ToLower(cfg.Encrypt))
+	if cfg.TrustServerCertificate {
+		q.Set("TrustServerCertificate", "true")
+	}
+	u := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(cfg.Username, password),
+		Host:     host,
+		RawQuery: q.Encode(),
+	}
+	if cfg.Instance != "" {
+		u.Path = "/" + cfg.Instance
+	}
+	return u.String()
+}
+
+// setValue sets the query parameter only when the value is set.
+func setValue(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"strings"
+)
+
+// ExecuteResult is the result of running sqldef against a deploy target.
+type ExecuteResult struct {
+	// Statements are the SQL statements executed, or to be executed in the dry run, in order.
+	Statements []string
+	// Output is the raw output of sqldef.
+	Output string
+}
+
+// NewExecuteResult parses the output of sqldef into the result.
+func NewExecuteResult(output string) ExecuteResult {
+	return ExecuteResult{
+		Statements: SplitStatements(output),
+		Output:     output,
+	}
+}
+
+// NoChanges returns true when sqldef has nothing to execute.
+func (r ExecuteResult) NoChanges() bool {
+	return len(r.Statements) == 0
+}
+
+// CountsByKind returns the number of statements for each kind, e.g. "CREATE TABLE" or "ALTER TABLE".
+func (r ExecuteResult) CountsByKind() map[string]int {
+	counts := make(map[string]int)
+	for _, stmt := range r.Statements {
+		counts[StatementKind(stmt)]++
+	}
+	return counts
+}
+
+// SplitStatements splits the output of sqldef into SQL statements.
+// Comments and transaction control statements are omitted.
+func SplitStatements(output string) []string {
+	var (
+		stmts   []string
+		current []string
+	)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		current = append(current, line)
+		if !strings.HasSuffix(line, ";") {
+			continue
+		}
+		stmt := strings.Join(current, " ")
+		current = nil
+		switch strings.ToUpper(strings.TrimSuffix(stmt, ";")) {
+		case "BEGIN", "COMMIT", "START TRANSACTION":
+			continue
+		}
+		stmts = append(stmts, stmt)
+	}
+	if len(current) > 0 {
+		stmts = append(stmts, strings.Join(current, " "))
+	}
+	return stmts
+}
+
+// objectKeywords are the types of the objects which DDL statements operate on.
+var objectKeywords = map[string]struct{}{
+	"TABLE":     {},
+	"VIEW":      {},
+	"INDEX":     {},
+	"SCHEMA":    {},
+	"DATABASE":  {},
+	"SEQUENCE":  {},
+	"TYPE":      {},
+	"DOMAIN":    {},
+	"EXTENSION": {},
+	"FUNCTION":  {},
+	"PROCEDURE": {},
+	"TRIGGER":   {},
+	"POLICY":    {},
+}
+
+// StatementKind returns the kind of the given statement,
+// which is the command followed by the type of the object for DDL, e.g. "CREATE INDEX" for "CREATE UNIQUE INDEX ...".
+func StatementKind(stmt string) string {
+	words := strings.Fields(strings.ToUpper(strings.TrimSuffix(stmt, ";")))
+	if len(words) == 0 {
+		return ""
+	}
+
+	command := words[0]
+	switch command {
+	case "CREATE", "ALTER", "DROP":
+		for _, w := range words[1:] {
+			if _, ok := objectKeywords[w]; ok {
+				return command + " " + w
+			}
+		}
+	}
+	return command
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	output := `-- dry run --
+BEGIN;
+CREATE TABLE posts (
+    id INT PRIMARY KEY
+);
+ALTER TABLE users DROP COLUMN email;
+COMMIT;
+`
+	assert.Equal(t, []string{
+		"CREATE TABLE posts ( id INT PRIMARY KEY );",
+		"ALTER TABLE users DROP COLUMN email;",
+	}, SplitStatements(output))
+}
+
+func TestStatementKind(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{stmt: "CREATE TABLE posts (id INT);", expected: "CREATE TABLE"},
+		{stmt: "create unique index idx_name on users (name);", expected: "CREATE INDEX"},
+		{stmt: "CREATE OR REPLACE VIEW v AS SELECT 1;", expected: "CREATE VIEW"},
+		{stmt: "ALTER TABLE users ADD COLUMN email TEXT;", expected: "ALTER TABLE"},
+		{stmt: "DROP INDEX CONCURRENTLY idx_name;", expected: "DROP INDEX"},
+		{stmt: "COMMENT ON TABLE users IS 'users';", expected: "COMMENT"},
+		{stmt: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, StatementKind(tt.stmt))
+		})
+	}
+}
+
+func TestExecuteResult(t *testing.T) {
+	t.Parallel()
+
+	r := NewExecuteResult("-- Nothing is modified --\n")
+	assert.True(t, r.NoChanges())
+	assert.Empty(t, r.CountsByKind())
+
+	r = NewExecuteResult(`BEGIN;
+CREATE TABLE posts (id INT);
+CREATE INDEX idx_title ON posts (title);
+CREATE INDEX idx_body ON posts (body);
+DROP TABLE logs;
+COMMIT;
+`)
+	assert.False(t, r.NoChanges())
+	assert.Equal(t, map[string]int{
+		"CREATE TABLE": 1,
+		"CREATE INDEX": 2,
+		"DROP TABLE":   1,
+	}, r.CountsByKind())
+}
@@ -18,68 +18,88 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
 	"os"
 	"os/exec"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
 )
 
-type SqldefProvider interface {
-	Init(logger sdk.StageLogPersister, username, password, host, port, dbName, schemaFilePath, execPath string)
-	ShowCurrentSchema(ctx context.Context) (string, error)
-	Execute(ctx context.Context, dryRun bool) error
+// Target is the database to run sqldef against.
+// It is passed by value to each call so that concurrent stages never share it.
+type Target struct {
+	// Config is the config of the deploy target to connect.
+	Config config.DeployTargetConfig
+	// ExecPath is the path to the sqldef command for the database type.
+	ExecPath string
+	// TargetTables are the regular expressions of the tables which sqldef manages.
+	// All tables are managed when empty.
+	TargetTables []string
+	// SkipTables are the regular expressions of the tables which sqldef must not manage.
+	SkipTables []string
+	// SkipViews makes sqldef not manage views.
+	SkipViews bool
 }
 
-type SqldefProviderImpl struct {
-	logger         sdk.StageLogPersister
-	username       string
-	password       string
-	host           string
-	port           string
-	DBName         string
-	SchemaFilePath string
-	execPath       string
+// SqldefProvider runs sqldef commands.
+// Implementations must be stateless so that they can be shared by concurrent stages.
+type SqldefProvider interface {
+	ShowCurrentSchema(ctx context.Context, target Target) (string, error)
+	Execute(ctx context.Context, logger sdk.StageLogPersister, target Target, schemaFiles []string, dryRun bool) (ExecuteResult, error)
 }
 
-// NewSqldef creates a new Sqldef instance.
-func (s *SqldefProviderImpl) Init(logger sdk.StageLogPersister, username, password, host, port, dbName, schemaFilePath, execPath string) {
-	s.logger = logger
-	s.username = username
-	s.password = password
-	s.host = host
-	s.port = port
-	s.DBName = dbName
-	s.SchemaFilePath = schemaFilePath
-	s.execPath = execPath
+type SqldefProviderImpl struct{}
+
+// newCommand builds the sqldef command connecting to the target database with the given additional args.
+// The returned cleanup function must be called after the command finishes.
+func newCommand(ctx context.Context, target Target, password string, args ...string) (*exec.Cmd, func(), error) {
+	connArgs, err := connectionArgs(target.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfgArgs, cleanup, err := configFileArgs(target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allArgs := append(connArgs, cfgArgs...)
+	allArgs = append(allArgs, args...)
+	allArgs = append(allArgs, databaseArg(target.Config))
+
+	cmd := exec.CommandContext(ctx, target.ExecPath, allArgs...)
+	cmd.Env = append(os.Environ(), connectionEnvs(target.Config, password)...)
+	return cmd, cleanup, nil
 }
 
-func (s *SqldefProviderImpl) ShowCurrentSchema(ctx context.Context) (string, error) {
-	args := []string{
-		"-u", s.username,
-		"-p", s.password,
-		"-h", s.host,
-		"-P", s.port,
-		"--export",
-		s.DBName,
+func (s *SqldefProviderImpl) ShowCurrentSchema(ctx context.Context, target Target) (string, error) {
+	password, err := target.Config.LoadPassword()
+	if err != nil {
+		return "", err
 	}
 
-	cmd := exec.CommandContext(ctx, s.execPath, args...)
+	cmd, cleanup, err := newCommand(ctx, target, password, "--export")
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
 	var outBuf, errBuf bytes.Buffer
 	cmd.Stdout = &outBuf
 	cmd.Stderr = &errBuf
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to run mysqldef: %w, stderr: %s", err, errBuf.String())
+		return "", fmt.Errorf("failed to run sqldef: %w, stderr: %s", err, redact(errBuf.String(), password))
 	}
 
 	return outBuf.String(), nil
 }
 
-func (s *SqldefProviderImpl) Execute(ctx context.Context, dryRun bool) error {
+// Execute runs sqldef to apply the schema files and returns the SQL statements
+// which are executed (or to be executed when dryRun is true).
+func (s *SqldefProviderImpl) Execute(ctx context.Context, logger sdk.StageLogPersister, target Target, schemaFiles []string, dryRun bool) (ExecuteResult, error) {
 	args := []string{
-		"-u", s.username,
-		"-p", s.password,
-		"-h", s.host,
-		"-P", s.port,
 		"--enable-drop",
 	}
 
@@ -87,31 +107,60 @@ func (s *SqldefProviderImpl) Execute(ctx context.Context, dryRun bool) error {
 		args = append(args, "--dry-run")
 	}
 
-	args = append(args, s.DBName)
+	schema, err := readSchema(schemaFiles)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
 
-	file, err := os.Open(s.SchemaFilePath)
+	password, err := target.Config.LoadPassword()
 	if err != nil {
-		return fmt.Errorf("failed to open schema file: %w", err)
+		return ExecuteResult{}, err
 	}
-	defer file.Close()
 
-	cmd := exec.CommandContext(ctx, s.execPath, args...)
-	cmd.Stdin = file
+	cmd, cleanup, err := newCommand(ctx, target, password, args...)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	defer cleanup()
+	cmd.Stdin = bytes.NewReader(schema)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("Execution failed: %w\nstderr: %s", err, stderr.String())
+		return ExecuteResult{}, fmt.Errorf("Execution failed: %w\nstderr: %s", err, redact(stderr.String(), password))
 	}
 
-	if dryRun {
-		s.logger.Info("Dry run mode: the following SQL statements would be executed:")
-	} else {
-		s.logger.Info("sqldef executed successfully. Output:")
+	result := NewExecuteResult(stdout.String())
+	switch {
+	case result.NoChanges():
+		logger.Info("No changes to the schema")
+		return result, nil
+	case dryRun:
+		logger.Infof("Dry run mode: the following %d SQL statements would be executed:", len(result.Statements))
+	default:
+		logger.Infof("sqldef executed %d SQL statements successfully:", len(result.Statements))
+	}
+	for _, stmt := range result.Statements {
+		logger.Info(redact(stmt, password))
 	}
-	s.logger.Info(stdout.String())
 
-	return nil
+	return result, nil
+}
+
+// readSchema concatenates the given schema files in order into the desired schema.
+func readSchema(files []string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file: %w", err)
+		}
+		buf.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,102 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+
+	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
+)
+
+func TestReadSchema(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tables := filepath.Join(dir, "tables.sql")
+	views := filepath.Join(dir, "views.sql")
+	require.NoError(t, os.WriteFile(tables, []byte("CREATE TABLE users (id INT);"), 0644))
+	require.NoError(t, os.WriteFile(views, []byte("CREATE VIEW v AS SELECT id FROM users;\n"), 0644))
+
+	got, err := readSchema([]string{tables, views})
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE users (id INT);\nCREATE VIEW v AS SELECT id FROM users;\n", string(got))
+
+	_, err = readSchema([]string{filepath.Join(dir, "missing.sql")})
+	assert.Error(t, err)
+}
+
+// writeFakeSqldef writes a script which prints its arguments, the password and the schema from stdin.
+func writeFakeSqldef(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mysqldef")
+	script := "#!/bin/sh\necho \"args: $*\"\necho \"password: $MYSQL_PWD\"\ncat\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestSqldefProviderImpl_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	execPath := writeFakeSqldef(t)
+	p := &SqldefProviderImpl{}
+
+	const n = 8
+	var wg sync.WaitGroup
+	outputs := make([]string, n)
+	errs := make([]error, n)
+	for i := range n {
+		dir := t.TempDir()
+		schemaFile := filepath.Join(dir, "schema.sql")
+		require.NoError(t, os.WriteFile(schemaFile, []byte(fmt.Sprintf("CREATE TABLE t%d (id INT);\n", i)), 0644))
+
+		target := Target{
+			Config: config.DeployTargetConfig{
+				DbType:   config.DBTypeMySQL,
+				Username: fmt.Sprintf("user%d", i),
+				Password: fmt.Sprintf("pass%d", i),
+				DBName:   fmt.Sprintf("db%d", i),
+			},
+			ExecPath: execPath,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Run plan and apply for different targets at the same time.
+			var result ExecuteResult
+			result, errs[i] = p.Execute(t.Context(), logpersistertest.NewTestLogPersister(t), target, []string{schemaFile}, i%2 == 0)
+			outputs[i] = result.Output
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		require.NoError(t, errs[i])
+		dryRun := ""
+		if i%2 == 0 {
+			dryRun = " --dry-run"
+		}
+		assert.Equal(t, fmt.Sprintf("args: -u user%d --enable-drop%s db%d\npassword: pass%d\nCREATE TABLE t%d (id INT);\n", i, dryRun, i, i, i), outputs[i])
+	}
+}
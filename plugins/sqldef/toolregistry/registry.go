@@ -20,12 +20,14 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/pipe-cd/community-plugins/plugins/sqldef/config"
 )
 
 const (
 	defaultSqldefVersion = "2.0.4"
+	defaultGhostVersion  = "1.1.6"
 )
 
 type client interface {
@@ -39,17 +41,53 @@ func NewRegistry(client client) *Registry {
 }
 
 // Registry provides functions to get path to the needed tools.
+// The installed paths are cached so that the deploy targets processed at the same time
+// do not install the same tool concurrently.
 type Registry struct {
 	client client
+
+	mu        sync.Mutex
+	installed map[string]string
+}
+
+// install installs the tool unless it has already been installed by this registry.
+func (r *Registry) install(ctx context.Context, name, version, script string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := name + "@" + version
+	if path, ok := r.installed[key]; ok {
+		return path, nil
+	}
+	path, err := r.client.InstallTool(ctx, name, version, script)
+	if err != nil {
+		return "", err
+	}
+	if r.installed == nil {
+		r.installed = make(map[string]string)
+	}
+	r.installed[key] = path
+	return path, nil
 }
 
 // DownloadBinary downloads the appropriate sqldef binary based on the database type.
-// Currently only supports MySQL, but can be extended for other database types.
+// Currently supports MySQL, PostgreSQL, SQLite and SQL Server.
 func (r *Registry) DownloadBinary(ctx context.Context, dbType config.DBType, version string) (string, error) {
 	switch dbType {
 	case config.DBTypeMySQL:
-		return r.client.InstallTool(ctx, "mysqldef", cmp.Or(version, defaultSqldefVersion), MysqldefInstallScript)
+		return r.install(ctx, "mysqldef", cmp.Or(version, defaultSqldefVersion), MysqldefInstallScript)
+	case config.DBTypePostgres:
+		return r.install(ctx, "psqldef", cmp.Or(version, defaultSqldefVersion), PsqldefInstallScript)
+	case config.DBTypeSQLite:
+		return r.install(ctx, "sqlite3def", cmp.Or(version, defaultSqldefVersion), Sqlite3defInstallScript)
+	case config.DBTypeMSSQL:
+		return r.install(ctx, "mssqldef", cmp.Or(version, defaultSqldefVersion), MssqldefInstallScript)
 	default:
-		return "", fmt.Errorf("unsupported database type: %s, currently only support: mysql", dbType)
+		return "", fmt.Errorf("unsupported database type: %s, currently only support: mysql, psql, sqlite, mssql", dbType)
 	}
 }
+
+// DownloadGhost downloads gh-ost to run online schema changes on MySQL.
+func (r *Registry) DownloadGhost(ctx context.Context, version string) (string, error) {
+	return r.install(ctx, "gh-ost", cmp.Or(version, defaultGhostVersion), GhostInstallScript)
+}
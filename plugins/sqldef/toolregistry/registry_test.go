@@ -31,6 +31,7 @@ func TestRegistry_DownloadBinary(t *testing.T) {
 		name        string
 		dbType      config.DBType
 		version     string
+		binary      string
 		expectError bool
 		errorMsg    string
 	}{
@@ -38,34 +39,50 @@ func TestRegistry_DownloadBinary(t *testing.T) {
 			name:        "MySQL_with_specific_version",
 			dbType:      config.DBTypeMySQL,
 			version:     "2.0.4",
+			binary:      "mysqldef",
 			expectError: false,
 		},
 		{
 			name:        "MySQL_with_empty_version_should_use_default",
 			dbType:      config.DBTypeMySQL,
 			version:     "",
+			binary:      "mysqldef",
 			expectError: false,
 		},
 		{
-			name:        "PostgreSQL_unsupported",
+			name:        "PostgreSQL_with_specific_version",
 			dbType:      config.DBTypePostgres,
 			version:     "2.0.4",
-			expectError: true,
-			errorMsg:    "unsupported database type: psql, currently only support: mysql",
+			binary:      "psqldef",
+			expectError: false,
+		},
+		{
+			name:        "PostgreSQL_with_empty_version_should_use_default",
+			dbType:      config.DBTypePostgres,
+			version:     "",
+			binary:      "psqldef",
+			expectError: false,
 		},
 		{
-			name:        "SQLite_unsupported",
+			name:        "SQLite_with_specific_version",
 			dbType:      config.DBTypeSQLite,
 			version:     "2.0.4",
-			expectError: true,
-			errorMsg:    "unsupported database type: sqlite, currently only support: mysql",
+			binary:      "sqlite3def",
+			expectError: false,
 		},
 		{
-			name:        "MSSQL_unsupported",
+			name:        "MSSQL_with_specific_version",
 			dbType:      config.DBTypeMSSQL,
 			version:     "2.0.4",
+			binary:      "mssqldef",
+			expectError: false,
+		},
+		{
+			name:        "Unknown_unsupported",
+			dbType:      config.DBType("oracle"),
+			version:     "2.0.4",
 			expectError: true,
-			errorMsg:    "unsupported database type: mssql, currently only support: mysql",
+			errorMsg:    "unsupported database type: oracle, currently only support: mysql, psql, sqlite, mssql",
 		},
 	}
 
@@ -84,20 +101,58 @@ func TestRegistry_DownloadBinary(t *testing.T) {
 				assert.NoError(t, err)
 				require.NotEmpty(t, path)
 
-				// For MySQL, verify the path contains the expected binary name
-				if tt.dbType == config.DBTypeMySQL {
-					assert.Contains(t, path, "mysqldef", "Expected mysqldef binary name in path")
-
-					// If version was provided, check it's in the path
-					if tt.version != "" {
-						assert.Contains(t, path, tt.version, "Expected version in path")
-					} else {
-						// If no version provided, should use default
-						assert.Contains(t, path, defaultSqldefVersion, "Expected default version in path")
-					}
+				// Verify the path contains the expected binary name
+				assert.Contains(t, path, tt.binary, "Expected binary name in path")
+
+				// If version was provided, check it's in the path
+				if tt.version != "" {
+					assert.Contains(t, path, tt.version, "Expected version in path")
+				} else {
+					// If no version provided, should use default
+					assert.Contains(t, path, defaultSqldefVersion, "Expected default version in path")
 				}
-				// TODO: Add more dbType cases when supported
 			}
 		})
 	}
 }
+
+func TestRegistry_DownloadGhost(t *testing.T) {
+	t.Parallel()
+
+	c := toolregistrytest.NewTestToolRegistry(t)
+	r := NewRegistry(c)
+
+	path, err := r.DownloadGhost(context.Background(), "")
+	require.NoError(t, err)
+	assert.Contains(t, path, "gh-ost")
+	assert.Contains(t, path, defaultGhostVersion)
+
+	_, err = r.DownloadGhost(context.Background(), "0.0.1")
+	assert.Error(t, err)
+}
+
+type countingClient struct {
+	calls int
+}
+
+func (c *countingClient) InstallTool(ctx context.Context, name, version, script string) (string, error) {
+	c.calls++
+	return "/tools/" + name + "-" + version, nil
+}
+
+func TestRegistry_CachesInstalledTools(t *testing.T) {
+	t.Parallel()
+
+	c := &countingClient{}
+	r := NewRegistry(c)
+
+	for range 3 {
+		path, err := r.DownloadBinary(context.Background(), config.DBTypeMySQL, "")
+		require.NoError(t, err)
+		assert.Equal(t, "/tools/mysqldef-"+defaultSqldefVersion, path)
+	}
+	_, err := r.DownloadBinary(context.Background(), config.DBTypePostgres, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, c.calls)
+}
@@ -20,3 +20,38 @@ curl -LO https://github.com/sqldef/sqldef/releases/download/v{{ .Version }}/mysq
 unzip mysqldef_{{ .Os }}_{{ .Arch }}.zip
 cp mysqldef {{ .OutPath }}
 `
+
+const PsqldefInstallScript = `
+cd {{ .TmpDir }}
+curl -LO https://github.com/sqldef/sqldef/releases/download/v{{ .Version }}/psqldef_{{ .Os }}_{{ .Arch }}.zip
+unzip psqldef_{{ .Os }}_{{ .Arch }}.zip
+cp psqldef {{ .OutPath }}
+`
+
+const Sqlite3defInstallScript = `
+cd {{ .TmpDir }}
+curl -LO https://github.com/sqldef/sqldef/releases/download/v{{ .Version }}/sqlite3def_{{ .Os }}_{{ .Arch }}.zip
+unzip sqlite3def_{{ .Os }}_{{ .Arch }}.zip
+cp sqlite3def {{ .OutPath }}
+`
+
+const MssqldefInstallScript = `
+cd {{ .TmpDir }}
+curl -LO https://github.com/sqldef/sqldef/releases/download/v{{ .Version }}/mssqldef_{{ .Os }}_{{ .Arch }}.zip
+unzip mssqldef_{{ .Os }}_{{ .Arch }}.zip
+cp mssqldef {{ .OutPath }}
+`
+
+// GhostInstallScript installs gh-ost, whose release assets are named with the build time of each version.
+const GhostInstallScript = `
+cd {{ .TmpDir }}
+case "{{ .Version }}" in
+  1.1.6) BUILD=20231207144046 ;;
+  *) echo "unsupported gh-ost version: {{ .Version }}" >&2; exit 1 ;;
+esac
+OS={{ .Os }}
+if [ "$OS" = "darwin" ]; then OS=osx; fi
+curl -LO https://github.com/github/gh-ost/releases/download/v{{ .Version }}/gh-ost-binary-${OS}-{{ .Arch }}-${BUILD}.tar.gz
+tar xzf gh-ost-binary-${OS}-{{ .Arch }}-${BUILD}.tar.gz
+cp gh-ost {{ .OutPath }}
+`